@@ -1,201 +1,902 @@
 package cmd
 
 import (
+	"bytes"
+	"cmp"
 	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
+	"os/exec"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
+)
+
+var (
+	// aliasTools selects the tools aliases are generated for
+	aliasTools []string
+	// aliasPrefix starts every alias name in place of k
+	aliasPrefix string
+	// aliasBin is the command the aliases run in place of kubectl
+	aliasBin string
+	// aliasSuffix is appended to every alias name
+	aliasSuffix string
+	// includeCategories limits the resources to these categories
+	includeCategories []string
+	// excludeCategories drops the resources in these categories
+	excludeCategories []string
+	// onlyResources limits the resources to these aliases
+	onlyResources []string
+	// checkConflicts reports aliases that shadow an executable on the PATH or an alias from conflictSources
+	checkConflicts bool
+	// conflictSources are rc files, or - for stdin, whose aliases the generated ones shouldn't shadow
+	conflictSources []string
+	// conflictStrategy resolves an alias that shadows an existing name
+	conflictStrategy string
+	// conflictPrefix is prepended to conflicting aliases by the prefix strategy
+	conflictPrefix string
+	// strictCollisions fails instead of warning when an alias expands to more than one command
+	strictCollisions bool
+	// namespaceShortcuts holds alias=namespace pairs, each added as a global op like sys
+	namespaceShortcuts []string
+	// denyVerbs lists operations, by alias or kubectl verb, that no alias is generated for
+	denyVerbs []string
+	// validateAgainstCluster warns about config resources the current cluster doesn't serve
+	validateAgainstCluster bool
+	// configPath points at a YAML file with parts to add to, or replace, the built-in ones, in place of the
+	// default config file, or - to read it from stdin
+	configPath string
+	// configFormat is the format the config is parsed as, inferred from its extension when empty
+	configFormat string
+	// aliasFormat selects how each generated alias is rendered
+	aliasFormat string
+	// aliasShell selects the shell syntax the aliases are written in
+	aliasShell string
+	// includeDeprecated adds legacy resources removed from newer Kubernetes versions
+	includeDeprecated bool
+	// includeAdvanced adds low-level resources that are mostly useful when debugging controllers
+	includeAdvanced bool
+	// includeAuth adds request-style auth resources that are created to get a review back
+	includeAuth bool
+	// includeCertManager adds the cert-manager CRDs
+	includeCertManager bool
+	// includeVPA adds the VerticalPodAutoscaler CRD
+	includeVPA bool
+	// compactAliases drops the resource from alias names when only one resource is in scope
+	compactAliases bool
+	// checkSyntax runs the generated output through the shell's syntax check before printing it
+	checkSyntax bool
+	// shellDetectHeader makes the output a no-op when sourced by a shell that can't parse it
+	shellDetectHeader bool
+	// guardBinary only defines the aliases when the base command is installed
+	guardBinary bool
+	// showPruned reports how many candidate combinations were accepted and rejected
+	showPruned bool
+	// suggestFixes proposes unused aliases for parts that reuse the alias of another part in their group
+	suggestFixes bool
+	// rolloutTimeout is baked into the rollout status aliases
+	rolloutTimeout string
+	// deleteWrapper is a command that delete aliases are routed through
+	deleteWrapper string
+	// normalizeAliases lowercases part aliases and strips characters that aren't valid in alias names
+	normalizeAliases bool
+	// fromCluster adds a resource for everything the current cluster serves that isn't built in
+	fromCluster bool
+	// maxClusterResources is how many resources --from-cluster can add before it warns, zero for no limit
+	maxClusterResources int
+	// maxClusterResourcesFail fails instead of warning when --from-cluster adds more than maxClusterResources
+	maxClusterResourcesFail bool
+	// clusterAllowlist drops resources the current cluster doesn't serve
+	clusterAllowlist bool
+	// combineOps generates the op chains defined in the config file
+	combineOps bool
+	// forceAliases adds the operations that bypass kubectl's safety checks, such as replace --force
+	forceAliases bool
+	// rbacAliases adds convenience aliases for kubectl auth can-i
+	rbacAliases bool
+	// exploreAliases adds convenience aliases for discovering resources with kubectl api-resources
+	exploreAliases bool
+	// describeFromKubectl comments each resource's aliases with its description from kubectl explain
+	describeFromKubectl bool
+	// flagStyle renders baked-in flag values as --flag=value or --flag value
+	flagStyle string
+	// qualifyGroups uses fully-qualified resource names in every expansion
+	qualifyGroups bool
+	// aliasSort orders the output instead of emitting aliases in generation order
+	aliasSort string
+	// aliasNamespaces lists namespaces to write a separate alias file for
+	aliasNamespaces []string
+	// outputPath is the file the aliases are written to instead of stdout
+	outputPath string
+	// outputDir is the directory the per-namespace alias files are written to
+	outputDir string
+	// outputPerm holds the octal permissions alias files are written with
+	outputPerm string
+	// countOnly prints the number of aliases instead of the aliases
+	countOnly bool
+	// finalNewline ends the output with a newline
+	finalNewline bool
+	// aliasGroupBy writes the output in sections by operation, resource or argument
+	aliasGroupBy string
+	// aliasTeam names a config file team whose namespace, resources and operations the aliases are limited to
+	aliasTeam string
+	// aliasPreset names a preset whose flags are applied before any others
+	aliasPreset string
+	// verbose reports adjustments made to generated aliases on stderr
+	verbose bool
 )
 
 func init() {
 	rootCmd.AddCommand(aliasesCmd)
+	aliasesCmd.PersistentFlags().StringSliceVar(&aliasTools, "tools", []string{"kubectl"}, "Tools to generate aliases for, any of: "+strings.Join(toolNames(), ", "))
+	aliasesCmd.PersistentFlags().StringVar(&aliasPrefix, "prefix", "k", "Start every alias name with this instead of k")
+	aliasesCmd.PersistentFlags().StringVar(&aliasBin, "bin", "kubectl", "Command the aliases run instead of kubectl, e.g. kubecolor or oc")
+	aliasesCmd.PersistentFlags().StringVar(&aliasSuffix, "suffix", "", "Append this to every alias name, e.g. 2 for kgpo2, so the aliases coexist with another set")
+	aliasesCmd.PersistentFlags().StringSliceVar(&includeCategories, "include", nil, "Only generate aliases for resources in these categories, e.g. core,cert-manager")
+	aliasesCmd.PersistentFlags().StringSliceVar(&excludeCategories, "exclude", nil, "Don't generate aliases for resources in these categories, e.g. istio")
+	aliasesCmd.PersistentFlags().StringSliceVar(&onlyResources, "resources", nil, "Only generate aliases for these resources, by alias, e.g. po,dep")
+	aliasesCmd.PersistentFlags().BoolVar(&checkConflicts, "check-conflicts", false, "Report aliases that shadow an executable on the PATH or an alias from --conflicts-from")
+	aliasesCmd.PersistentFlags().StringSliceVar(&conflictSources, "conflicts-from", nil, "rc files, or - for stdin, with existing aliases to check for conflicts (requires --check-conflicts)")
+	aliasesCmd.PersistentFlags().StringVar(&conflictStrategy, "on-conflict", "override", "How to resolve a conflicting alias, one of: "+conflictStrategyNames())
+	aliasesCmd.PersistentFlags().StringVar(&conflictPrefix, "conflict-prefix", "_", "Prepended to conflicting aliases with --on-conflict prefix")
+	aliasesCmd.PersistentFlags().BoolVar(&strictCollisions, "strict", false, "Fail instead of warning when an alias expands to more than one command")
+	aliasesCmd.PersistentFlags().StringSliceVar(&namespaceShortcuts, "namespace-shortcuts", nil, "Add a global op like sys for each alias=namespace, e.g. mon=monitoring,ist=istio-system")
+	aliasesCmd.PersistentFlags().StringSliceVar(&denyVerbs, "deny-verbs", nil, "Never generate aliases for these operations, by alias or kubectl verb, e.g. rm,patch")
+	aliasesCmd.PersistentFlags().BoolVar(&validateAgainstCluster, "validate-against-cluster", false, "Warn about config resources the current cluster doesn't serve (requires a config file)")
+	aliasesCmd.PersistentFlags().StringVar(&configPath, "config", "", "YAML or JSON file, or - for stdin, with parts to add to, or replace, the built-in ones (default ~/.kube-tools/aliases.yaml if it exists)")
+	aliasesCmd.PersistentFlags().StringVar(&configFormat, "config-format", "", "Parse the config as yaml or json (default json for a .json file, else yaml)")
+	aliasesCmd.PersistentFlags().StringVar(&aliasFormat, "format", "shell", "Output format, one of: shell, fzf, assoc-array, tmux, zsh-abbr, fish-abbr, toml, json, markdown")
+	aliasesCmd.PersistentFlags().StringVar(&aliasShell, "shell", "bash", "Shell to generate aliases for, one of: bash, zsh, fish, powershell")
+	aliasesCmd.PersistentFlags().BoolVar(&includeDeprecated, "include-deprecated", false, "Include deprecated resources for older clusters")
+	aliasesCmd.PersistentFlags().BoolVar(&includeAdvanced, "advanced", false, "Include low-level resources such as controllerrevisions and endpointslices")
+	aliasesCmd.PersistentFlags().BoolVar(&includeAuth, "auth", false, "Include tokenreviews and subjectaccessreviews for auditing auth")
+	aliasesCmd.PersistentFlags().BoolVar(&includeCertManager, "cert-manager", false, "Include cert-manager certificates, issuers and clusterissuers")
+	aliasesCmd.PersistentFlags().BoolVar(&includeVPA, "vpa", false, "Include verticalpodautoscalers (requires the VPA CRDs)")
+	aliasesCmd.PersistentFlags().BoolVar(&compactAliases, "compact", false, "Drop the resource from alias names when exactly one resource is in scope")
+	aliasesCmd.PersistentFlags().BoolVar(&checkSyntax, "check-syntax", false, "Fail if the generated output is not valid syntax for the selected shell (requires the shell)")
+	aliasesCmd.PersistentFlags().BoolVar(&shellDetectHeader, "shell-detect-header", false, "Start the output with a guard that skips it in shells that can't run it")
+	aliasesCmd.PersistentFlags().BoolVar(&guardBinary, "guard-binary", false, "Only define the aliases if kubectl is found on the PATH")
+	aliasesCmd.PersistentFlags().BoolVar(&showPruned, "show-pruned", false, "Report accepted and rejected combinations on stderr")
+	aliasesCmd.PersistentFlags().BoolVar(&suggestFixes, "suggest-fixes", false, "Suggest unused aliases on stderr for parts that reuse the alias of another part in their group")
+	aliasesCmd.PersistentFlags().StringVar(&rolloutTimeout, "rollout-timeout", aliases.DefaultRolloutTimeout, "Timeout baked into rollout status aliases, empty to wait indefinitely")
+	aliasesCmd.PersistentFlags().StringVar(&deleteWrapper, "delete-wrapper", "", "Route delete aliases through this command, e.g. a confirmation script")
+	aliasesCmd.PersistentFlags().BoolVar(&normalizeAliases, "normalize-aliases", false, "Lowercase part aliases and strip characters that aren't valid in alias names")
+	aliasesCmd.PersistentFlags().BoolVar(&fromCluster, "from-cluster", false, "Add aliases for every resource the current cluster serves, including CRDs, using their short names")
+	aliasesCmd.PersistentFlags().IntVar(&maxClusterResources, "max-resources-per-alias", 50, "Warn when --from-cluster adds more resources than this, 0 for no limit")
+	aliasesCmd.PersistentFlags().BoolVar(&maxClusterResourcesFail, "max-resources-fail", false, "Fail instead of warning when --from-cluster adds more resources than --max-resources-per-alias")
+	aliasesCmd.PersistentFlags().BoolVar(&clusterAllowlist, "resource-allowlist-from-cluster", false, "Only generate aliases for resources served by the current cluster")
+	aliasesCmd.PersistentFlags().BoolVar(&exploreAliases, "explore-aliases", false, "Include convenience aliases for discovering resources with api-resources")
+	aliasesCmd.PersistentFlags().BoolVar(&combineOps, "combine-ops", false, "Generate the op chains defined under chains in the config file, e.g. get then logs")
+	aliasesCmd.PersistentFlags().BoolVar(&forceAliases, "force-aliases", false, "Include operations that bypass kubectl's safety checks, such as replace --force")
+	aliasesCmd.PersistentFlags().BoolVar(&rbacAliases, "rbac-aliases", false, "Include convenience aliases for checking permissions with auth can-i")
+	aliasesCmd.PersistentFlags().BoolVar(&describeFromKubectl, "describe-from-kubectl", false, "Comment each resource's aliases with its description from kubectl explain")
+	aliasesCmd.PersistentFlags().StringVar(&flagStyle, "flag-style", "equals", "Render flag values as --flag=value (equals) or --flag value (space)")
+	aliasesCmd.PersistentFlags().BoolVar(&qualifyGroups, "qualify-groups", false, "Qualify resource names with their API group, e.g. ingress.networking.k8s.io")
+	aliasesCmd.PersistentFlags().StringVar(&aliasSort, "sort", "", "Sort the aliases, one of: name, command, length")
+	aliasesCmd.PersistentFlags().StringSliceVar(&aliasNamespaces, "namespaces", nil, "Write one alias file per namespace with the namespace baked in (requires --output-dir)")
+	aliasesCmd.PersistentFlags().StringVarP(&outputPath, "output", "o", "", "File to write the aliases to instead of stdout, replaced atomically")
+	aliasesCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "Directory to write the per-namespace alias files to")
+	aliasesCmd.PersistentFlags().StringVar(&outputPerm, "output-perm", "0644", "Octal permissions of the alias files written by --output, --output-dir and install")
+	aliasesCmd.PersistentFlags().BoolVar(&countOnly, "count-only", false, "Print only the number of generated aliases")
+	aliasesCmd.PersistentFlags().BoolVar(&finalNewline, "final-newline", true, "End the output with a trailing newline")
+	aliasesCmd.PersistentFlags().StringVar(&aliasGroupBy, "group-by", "", "Write the aliases in sections, one of: operation, resource, argument")
+	aliasesCmd.PersistentFlags().StringVar(&aliasTeam, "team", "", "Only generate the aliases of this config file team, with its namespace baked in")
+	aliasesCmd.PersistentFlags().StringVar(&aliasPreset, "preset", "", "Apply a bundle of flags, one of: "+strings.Join(presetNames(presets), ", ")+" or a config file preset")
+	aliasesCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Report adjustments made to generated aliases on stderr")
 }
 
+// aliasNamePattern matches the characters that can be used in an alias name in every supported shell
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+
 var aliasesCmd = &cobra.Command{
 	Use:   "aliases",
 	Short: "Generates aliases for kubectl",
 	Long: "Generates shorthand aliases for kubectl, e.g. 'kubectl get pods' becomes 'kgpo'." +
 		"\nHeavily inspired by the https://github.com/ahmetb/kubectl-aliases project, just converted to Golang",
-	Run: func(cmd *cobra.Command, args []string) {
-		main()
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if configFormat != "" && configFormat != "yaml" && configFormat != "json" {
+			return fmt.Errorf("unknown config format %q, expected yaml or json", configFormat)
+		}
+		if aliasPreset != "" {
+			available, err := availablePresets()
+			if err != nil {
+				return err
+			}
+			if err := applyPreset(cmd, available, aliasPreset); err != nil {
+				return err
+			}
+		}
+		switch aliasFormat {
+		case "shell", "fzf", "assoc-array", "tmux", "zsh-abbr", "fish-abbr", "toml", "json", "markdown":
+		default:
+			return fmt.Errorf("unknown format %q, expected shell, fzf, assoc-array, tmux, zsh-abbr, fish-abbr, toml, json or markdown", aliasFormat)
+		}
+		switch aliasShell {
+		case "bash", "zsh", "fish", "powershell":
+		default:
+			return fmt.Errorf("unknown shell %q, expected bash, zsh, fish or powershell", aliasShell)
+		}
+		if err := formatShellError(aliasFormat, aliasShell); err != nil {
+			return err
+		}
+		if (aliasShell == "fish" || aliasShell == "powershell") && shellDetectHeader {
+			return fmt.Errorf("--shell-detect-header can't guard %s, which parses the whole file before running it", aliasShell)
+		}
+		if !slices.Contains(conflictStrategies, conflictStrategy) {
+			return fmt.Errorf("unknown conflict strategy %q, expected %s", conflictStrategy, conflictStrategyNames())
+		}
+		if len(conflictSources) > 0 && !checkConflicts {
+			return fmt.Errorf("--conflicts-from requires --check-conflicts")
+		}
+		if !aliasNamePattern.MatchString(conflictPrefix) {
+			return fmt.Errorf("invalid --conflict-prefix %q, expected letters, digits and underscores", conflictPrefix)
+		}
+		if _, ok := aliasSorts[aliasSort]; aliasSort != "" && !ok {
+			return fmt.Errorf("unknown sort %q, expected name, command or length", aliasSort)
+		}
+		if _, ok := aliasGroupings[aliasGroupBy]; aliasGroupBy != "" && !ok {
+			return fmt.Errorf("unknown grouping %q, expected operation, resource or argument", aliasGroupBy)
+		}
+		if aliasGroupBy != "" && (aliasFormat == "json" || aliasFormat == "markdown") {
+			return fmt.Errorf("--group-by doesn't apply to the %s format", aliasFormat)
+		}
+		for _, tool := range aliasTools {
+			if !slices.Contains(toolNames(), tool) {
+				return fmt.Errorf("unknown tool %q, expected any of %s", tool, strings.Join(toolNames(), ", "))
+			}
+		}
+		for _, shortcut := range namespaceShortcuts {
+			alias, namespace, _ := strings.Cut(shortcut, "=")
+			if alias == "" || !aliasNamePattern.MatchString(alias) || !aliases.NamespacePattern.MatchString(namespace) {
+				return fmt.Errorf("invalid --namespace-shortcuts entry %q, expected alias=namespace, e.g. mon=monitoring", shortcut)
+			}
+		}
+		if aliasPrefix == "" || !aliasNamePattern.MatchString(aliasPrefix) {
+			return fmt.Errorf("invalid --prefix %q, expected one or more letters, digits and underscores", aliasPrefix)
+		}
+		if strings.TrimSpace(aliasBin) == "" {
+			return fmt.Errorf("--bin can't be empty")
+		}
+		if !aliasNamePattern.MatchString(aliasSuffix) {
+			return fmt.Errorf("invalid --suffix %q, expected letters, digits and underscores", aliasSuffix)
+		}
+		if _, err := outputFileMode(); err != nil {
+			return err
+		}
+		if maxClusterResources < 0 {
+			return fmt.Errorf("invalid --max-resources-per-alias %d, expected zero or more", maxClusterResources)
+		}
+		if flagStyle != "equals" && flagStyle != "space" {
+			return fmt.Errorf("unknown flag style %q, expected equals or space", flagStyle)
+		}
+		if rolloutTimeout != "" {
+			if _, err := time.ParseDuration(rolloutTimeout); err != nil {
+				return fmt.Errorf("invalid --rollout-timeout: %v", err)
+			}
+		}
+		if checkSyntax && !isScriptFormat(aliasFormat) {
+			return fmt.Errorf("--check-syntax only applies to the shell, assoc-array, zsh-abbr and fish-abbr formats")
+		}
+		if shellDetectHeader && !isScriptFormat(aliasFormat) {
+			return fmt.Errorf("--shell-detect-header only applies to the shell, assoc-array, zsh-abbr and fish-abbr formats")
+		}
+		if combineOps && aliasFormat != "shell" {
+			return fmt.Errorf("--combine-ops only applies to the shell format, as each chain is a function")
+		}
+		if guardBinary && !isScriptFormat(aliasFormat) {
+			return fmt.Errorf("--guard-binary only applies to the shell, assoc-array, zsh-abbr and fish-abbr formats")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAliases()
 	},
 }
 
-// Part represents a single part of a command (e.g., an operation or a resource)
-type Part struct {
-	Alias            string
-	Full             string
-	AllowWhenOneOf   []string
-	IncompatibleWith []string
+// isScriptFormat reports whether the format produces a script that a shell can source
+func isScriptFormat(format string) bool {
+	return format == "shell" || format == "assoc-array" || format == "zsh-abbr" || format == "fish-abbr"
 }
 
-// AliasGenerator holds the configuration for generating aliases
+// hasComments reports whether the format's output can carry '#' comment lines between the aliases
+func hasComments(format string) bool {
+	return format != "fzf" && format != "json" && format != "markdown"
+}
+
+// Part and Alias are the generator's, so the config file and renderers can use them directly
+type (
+	Part  = aliases.Part
+	Alias = aliases.Alias
+)
+
+// AliasGenerator renders the aliases of the embedded generator in the selected format
 type AliasGenerator struct {
-	Commands  []Part
-	GlobalOps []Part
-	Ops       []Part
-	Resources []Part
-	Args      []Part
-	PosArgs   []Part
+	aliases.Generator
+	// Out is where aliases are written, defaulting to stdout
+	Out io.Writer
+	// GroupBy names one of aliasGroupings to write the output in sections by, leaving it ungrouped when empty
+	GroupBy string
+	// Sort names one of aliasSorts to order the output by, leaving generation order when empty
+	Sort string
+	// Shell is the shell whose syntax the shell format is written in, defaulting to bash
+	Shell string
+	// Descriptions maps resource expansions to a description that is commented above their first alias
+	Descriptions map[string]string
+	// Conflicts resolves aliases that shadow existing names, when set
+	Conflicts *Conflicts
+	// Strict fails generation when two combinations produce the same alias with different commands,
+	// instead of warning
+	Strict bool
+
+	described map[string]struct{}
 }
 
-// generate generates and prints all valid aliases based on the generator's configuration
-func (ag *AliasGenerator) generate() {
-	for _, cmd := range ag.Commands {
-		ag.combine([]Part{cmd}, ag.GlobalOps, 1)
-	}
+// aliasGroupings maps each --group-by dimension to the alias field it buckets on
+var aliasGroupings = map[string]func(alias Alias) string{
+	"operation": func(alias Alias) string { return alias.Operation },
+	"resource":  func(alias Alias) string { return alias.Resource },
+	"argument":  func(alias Alias) string { return alias.Argument },
 }
 
-// combine recursively combines parts and checks their validity
-func (ag *AliasGenerator) combine(current []Part, next []Part, depth int) {
-	if depth == 6 { // Reached the end of the combination chain
-		ag.printAlias(current)
-		return
+// writeGrouped writes the aliases in sections keyed on the GroupBy dimension, each headed by a comment.
+// Sections appear in the order their first alias does, aliases without a part in that group come last
+func (ag *AliasGenerator) writeGrouped(aliases []Alias) {
+	keys, sections := groupAliases(aliases, aliasGroupings[ag.GroupBy])
+	for i, k := range keys {
+		if hasComments(aliasFormat) {
+			if i > 0 {
+				fmt.Fprintln(ag.Out)
+			}
+			header := k
+			if header == "" {
+				header = "no " + ag.GroupBy
+			}
+			fmt.Fprintln(ag.Out, comment(header))
+		}
+		for _, alias := range sections[k] {
+			ag.writeAlias(alias)
+		}
 	}
+}
 
-	for _, part := range next {
-		if ag.isValidCombination(current, part) {
-			ag.nextStep(append(current, part), depth+1)
+// groupAliases buckets the aliases by key, returning the keys in the order their first alias appears with the
+// empty key, for aliases that have none, last
+func groupAliases(aliases []Alias, key func(alias Alias) string) ([]string, map[string][]Alias) {
+	var keys []string
+	sections := make(map[string][]Alias)
+	for _, alias := range aliases {
+		k := key(alias)
+		if _, exists := sections[k]; !exists && k != "" {
+			keys = append(keys, k)
 		}
+		sections[k] = append(sections[k], alias)
 	}
+	if _, exists := sections[""]; exists {
+		keys = append(keys, "")
+	}
+	return keys, sections
+}
 
-	// Try without adding a new part from the current group
-	ag.nextStep(current, depth+1)
+// aliasSorts maps each sort mode to the keys it compares, in order of precedence
+var aliasSorts = map[string][]func(a, b Alias) int{
+	"name":    {byName},
+	"command": {byCommand, byName},
+	"length":  {byLength, byName},
 }
 
-// nextStep decides which group of parts to combine next based on the current depth
-func (ag *AliasGenerator) nextStep(current []Part, depth int) {
-	switch depth {
-	case 2:
-		ag.combine(current, ag.Ops, depth)
-	case 3:
-		ag.combine(current, ag.Resources, depth)
-	case 4:
-		ag.combine(current, ag.Args, depth)
-	case 5:
-		ag.combine(current, ag.PosArgs, depth)
+func byName(a, b Alias) int    { return cmp.Compare(a.Name, b.Name) }
+func byCommand(a, b Alias) int { return cmp.Compare(a.Command, b.Command) }
+func byLength(a, b Alias) int  { return cmp.Compare(len(a.Name), len(b.Name)) }
+
+// sortAliases orders aliases by each key in turn, falling through to the next key on ties
+func sortAliases(aliases []Alias, keys []func(a, b Alias) int) {
+	slices.SortStableFunc(aliases, func(a, b Alias) int {
+		for _, key := range keys {
+			if c := key(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// write renders the generated aliases to Out in the selected format, sorted and grouped as configured
+func (ag *AliasGenerator) write(generated []Alias) error {
+	if ag.Out == nil {
+		ag.Out = os.Stdout
+	}
+	// Collisions are checked across the whole set, before any alias is written
+	if collisions := aliases.FindCollisions(generated); len(collisions) > 0 {
+		if ag.Strict {
+			return fmt.Errorf("colliding aliases\n%s", indent(collisionError(collisions).Error()))
+		}
+		warnCollisions(collisions)
+	}
+	generated = aliases.Dedup(generated)
+
+	if ag.Sort != "" {
+		sortAliases(generated, aliasSorts[ag.Sort])
+	}
+	switch {
+	case aliasFormat == "json":
+		return ag.writeJSON(generated)
+	case aliasFormat == "markdown":
+		ag.writeMarkdown(generated)
 	default:
-		ag.combine(current, []Part{}, depth)
+		ag.writeGuarded(generated)
 	}
+	return nil
 }
 
-// isValidCombination checks if adding a new part to the current combination is valid
-func (ag *AliasGenerator) isValidCombination(current []Part, newPart Part) bool {
-	currentAliases := make(map[string]struct{})
-	for _, part := range current {
-		currentAliases[part.Alias] = struct{}{}
-		for _, incompatible := range part.IncompatibleWith {
-			if incompatible == newPart.Alias {
-				return false
+// writeGuarded writes the aliases in a script or list format. With --guard-binary the aliases of each binary are
+// wrapped in a guard of their own, so a tool that isn't installed only drops its own aliases. The associative array
+// is then declared up front and each guard adds its aliases to it
+func (ag *AliasGenerator) writeGuarded(generated []Alias) {
+	binaries, blocks := []string{""}, map[string][]Alias{"": generated}
+	if guardBinary {
+		binaries, blocks = groupAliases(generated, ag.binary)
+		if aliasFormat == "assoc-array" {
+			fmt.Fprintln(ag.Out, "declare -A KUBE_ALIASES")
+		}
+	}
+	for _, binary := range binaries {
+		// An alias without a command has no binary to check, and isn't guarded
+		guard := binaryCheck(ag.shell(), []Part{{Full: binary}})
+		if guard != "" {
+			switch ag.shell() {
+			case "fish":
+				fmt.Fprintf(ag.Out, "if %s\n", guard)
+			case "powershell":
+				fmt.Fprintf(ag.Out, "if %s {\n", guard)
+			default:
+				fmt.Fprintf(ag.Out, "if %s; then\n", guard)
+			}
+		}
+		if aliasFormat == "assoc-array" && guardBinary {
+			fmt.Fprintln(ag.Out, "KUBE_ALIASES+=(")
+		} else if aliasFormat == "assoc-array" {
+			fmt.Fprintln(ag.Out, "declare -A KUBE_ALIASES=(")
+		}
+		if ag.GroupBy != "" {
+			ag.writeGrouped(blocks[binary])
+		} else {
+			for _, alias := range blocks[binary] {
+				ag.writeAlias(alias)
 			}
 		}
+		if aliasFormat == "assoc-array" {
+			fmt.Fprintln(ag.Out, ")")
+		}
+		if guard != "" {
+			switch ag.shell() {
+			case "fish":
+				fmt.Fprintln(ag.Out, "end")
+			case "powershell":
+				fmt.Fprintln(ag.Out, "}")
+			default:
+				fmt.Fprintln(ag.Out, "fi")
+			}
+		}
+	}
+}
+
+// binary returns the binary the alias runs, or "" when its command is empty
+func (ag *AliasGenerator) binary(alias Alias) string {
+	fields := strings.Fields(ag.completedCommand(alias))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// completedCommand returns the command the alias runs, without the delete wrapper it may be routed through,
+// which is what the alias should complete as
+func (ag *AliasGenerator) completedCommand(alias Alias) string {
+	if alias.Function && ag.DeleteWrapper != "" {
+		return strings.TrimPrefix(alias.Command, ag.DeleteWrapper+" ")
 	}
+	return alias.Command
+}
 
-	if len(newPart.AllowWhenOneOf) > 0 {
-		found := false
-		for _, allowed := range newPart.AllowWhenOneOf {
-			if _, exists := currentAliases[allowed]; exists {
-				found = true
-				break
+// warnCollisions reports each alias that expands to more than one command on stderr
+func warnCollisions(collisions map[string][]string) {
+	if err := collisionError(collisions); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: colliding aliases, keeping the first command of each\n%s\n", indent(err.Error()))
+	}
+}
+
+// writeAlias writes a single alias in the selected format
+func (ag *AliasGenerator) writeAlias(alias Alias) {
+	if description, ok := ag.Descriptions[alias.Resource]; ok && hasComments(aliasFormat) {
+		if _, done := ag.described[alias.Resource]; !done {
+			if ag.described == nil {
+				ag.described = make(map[string]struct{})
 			}
+			ag.described[alias.Resource] = struct{}{}
+			fmt.Fprintln(ag.Out, comment(alias.Resource+": "+description))
+		}
+	}
+
+	switch aliasFormat {
+	case "fzf":
+		fmt.Fprintf(ag.Out, "%s\t%s\n", alias.Name, alias.Command)
+	case "assoc-array":
+		fmt.Fprintf(ag.Out, "  [%s]=%s\n", alias.Name, singleQuote("bash", alias.Command))
+	case "zsh-abbr":
+		fmt.Fprintf(ag.Out, "abbr %s=%s\n", alias.Name, singleQuote("zsh", alias.Command))
+	case "fish-abbr":
+		fmt.Fprintf(ag.Out, "abbr -a %s %s\n", alias.Name, singleQuote("fish", alias.Command))
+	case "toml":
+		fmt.Fprintf(ag.Out, "%s = %s\n", tomlString(alias.Name), tomlString(alias.Command))
+	case "tmux":
+		fmt.Fprintln(ag.Out, tmuxCommandAlias(alias))
+	default:
+		definition := shellDefinition(ag.shell(), alias)
+		if alias.Deprecated {
+			definition += " " + comment("deprecated")
+		}
+		fmt.Fprintln(ag.Out, definition)
+	}
+}
+
+// shellDefinition returns the alias, or the function for aliases that need one, in the shell's syntax
+func shellDefinition(shell string, alias Alias) string {
+	if len(alias.Steps) > 0 {
+		return chainDefinition(shell, alias)
+	}
+	// PowerShell aliases can't carry arguments, so every alias is a function passing its arguments on
+	if shell == "powershell" {
+		return fmt.Sprintf("function %s { %s @args }", alias.Name, alias.Command)
+	}
+	if shell == "fish" {
+		if alias.Function {
+			return fmt.Sprintf("function %s; %s $argv; end", alias.Name, alias.Command)
+		}
+		return fmt.Sprintf("alias %s %s", alias.Name, singleQuote(shell, alias.Command))
+	}
+	if alias.Function {
+		return fmt.Sprintf("%s() { %s \"$@\"; }", alias.Name, alias.Command)
+	}
+	return fmt.Sprintf("alias %s=%s", alias.Name, singleQuote(shell, alias.Command))
+}
+
+// chainDefinition returns the function running each step of a chained alias with the alias's arguments, as long
+// as the previous step succeeded
+func chainDefinition(shell string, alias Alias) string {
+	var steps []string
+	switch shell {
+	case "powershell":
+		for _, step := range alias.Steps {
+			steps = append(steps, step+" @args")
+		}
+		body := strings.Join(steps, "; if ($?) { ") + strings.Repeat(" }", len(steps)-1)
+		return fmt.Sprintf("function %s { %s }", alias.Name, body)
+	case "fish":
+		for _, step := range alias.Steps {
+			steps = append(steps, step+" $argv")
+		}
+		return fmt.Sprintf("function %s; %s; end", alias.Name, strings.Join(steps, "; and "))
+	default:
+		for _, step := range alias.Steps {
+			steps = append(steps, step+` "$@"`)
 		}
-		return found
+		return fmt.Sprintf("%s() { %s; }", alias.Name, strings.Join(steps, " && "))
+	}
+}
+
+// formatShellError reports why the format can't be sourced by the shell: the abbreviation formats only work in the
+// shell they're named after, and associative arrays only in bash and zsh
+func formatShellError(format, shell string) error {
+	if (shell == "fish" || shell == "powershell") && format == "assoc-array" {
+		return fmt.Errorf("the %s format isn't supported by %s", format, shell)
+	}
+	if format == "zsh-abbr" && shell != "zsh" {
+		return fmt.Errorf("the zsh-abbr format requires --shell zsh")
+	}
+	if format == "fish-abbr" && shell != "fish" {
+		return fmt.Errorf("the fish-abbr format requires --shell fish")
 	}
+	return nil
+}
 
-	return true
+// singleQuote quotes s in single quotes for the shell. bash and zsh can't escape inside single quotes,
+// so a quote has to close and reopen them, while fish accepts backslash escapes
+func singleQuote(shell string, s string) string {
+	if shell == "fish" {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
-// printAlias prints the alias for the current combination
-func (ag *AliasGenerator) printAlias(combination []Part) {
-	alias := ""
-	full := ""
-	for _, part := range combination {
-		alias += part.Alias
-		full += part.Full + " "
+// shell returns the shell the output is written for, defaulting to bash
+func (ag *AliasGenerator) shell() string {
+	if ag.Shell == "" {
+		return "bash"
 	}
-	fmt.Printf("alias %s='%s'\n", alias, strings.TrimSpace(full))
+	return ag.Shell
 }
 
-func main() {
+// comment renders text as a comment in the output's shell syntax. Every supported shell uses '#' line
+// comments, so all comments in the output should go through here to keep them valid when that changes
+func comment(text string) string {
+	return "# " + text
+}
+
+// runAliases builds the generator from the command flags and prints the aliases
+func runAliases() error {
+	if countOnly {
+		ag, err := newAliasGenerator()
+		if err != nil {
+			return err
+		}
+		fmt.Println(len(aliases.Dedup(ag.Generate())))
+		return nil
+	}
+
+	if len(aliasNamespaces) > 0 || outputDir != "" {
+		if len(aliasNamespaces) == 0 || outputDir == "" {
+			return fmt.Errorf("--namespaces and --output-dir must be used together")
+		}
+		if outputPath != "" {
+			return fmt.Errorf("--output can't be used with --namespaces, the files are written to --output-dir")
+		}
+		return writeNamespaceFiles(aliasNamespaces, outputDir)
+	}
+
+	out, err := renderAliases()
+	if err != nil {
+		return err
+	}
+	if outputPath == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	out = addGeneratedHeader(out, time.Now())
+	mode, err := outputFileMode()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomicMode(outputPath, out, mode)
+}
+
+// renderAliases builds the generator from the command flags and returns the rendered aliases
+func renderAliases() ([]byte, error) {
+	ag, err := newAliasGenerator()
+	if err != nil {
+		return nil, err
+	}
+	return render(&ag)
+}
+
+// newAliasGenerator builds the generator from the built-in parts, the config file and the command flags
+func newAliasGenerator() (AliasGenerator, error) {
+	path := configFile()
+	var config Config
+	if path != "" {
+		loaded, err := loadConfig(path)
+		if err != nil {
+			return AliasGenerator{}, err
+		}
+		config = *loaded
+	}
+	return buildAliasGenerator(config, path)
+}
+
+// buildAliasGenerator builds the generator from the built-in parts, the given config loaded from path, if any,
+// and the command flags
+func buildAliasGenerator(config Config, path string) (AliasGenerator, error) {
+	if validateAgainstCluster {
+		if path == "" {
+			return AliasGenerator{}, fmt.Errorf("--validate-against-cluster requires a config file")
+		}
+		warnUnknownResources(config.Resources)
+	}
+
+	opts := aliasOptions()
+	if fromCluster {
+		opts.AddResources = func(resources []Part, taken map[string]struct{}) ([]Part, error) {
+			// Only resources that can be listed make sense with the get, describe and delete operations
+			served, err := discoverAPIResources("--verbs=get,list")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: skipping cluster resources, %v\n", err)
+				return nil, nil
+			}
+			added := clusterResources(resources, served, taken)
+			if err := checkClusterResources(added, os.Stderr); err != nil {
+				return nil, err
+			}
+			return added, nil
+		}
+	}
+	if clusterAllowlist {
+		opts.KeepResources = func(resources []Part) []Part {
+			served, err := discoverAPIResources()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: keeping all resources, %v\n", err)
+				return resources
+			}
+			return intersectResources(resources, served)
+		}
+	}
+	g, err := aliases.FromConfig(config, opts)
+	if err != nil {
+		return AliasGenerator{}, err
+	}
+
 	ag := AliasGenerator{
-		Commands: []Part{
-			{"k", "kubectl", nil, nil},
-		},
-		GlobalOps: []Part{
-			{"sys", "--namespace=kube-system", nil, nil},
-		},
-		Ops:       generateOperations(),
-		Resources: generateResources(),
-		Args:      generateArguments(),
-		PosArgs:   generatePositionalArgs(generateResourceTypes(generateResources())),
-	}
-
-	if len(os.Args) > 1 && (os.Args[1] == "bash" || os.Args[1] == "zsh" || os.Args[1] == "fish") {
-		fmt.Printf("# Generated aliases for %s\n", os.Args[1])
-	}
-
-	ag.generate()
-}
-
-func generateOperations() []Part {
-	return []Part{
-		{"a", "apply --recursive -f", nil, nil},
-		{"ak", "apply -k", nil, []string{"sys"}},
-		{"k", "kustomize", nil, []string{"sys"}},
-		{"ex", "exec -i -t", nil, nil},
-		{"lo", "logs -f", nil, nil},
-		{"lop", "logs -f -p", nil, nil},
-		{"p", "proxy", nil, []string{"sys"}},
-		{"pf", "port-forward", nil, []string{"sys"}},
-		{"g", "get", nil, nil},
-		{"d", "describe", nil, []string{"sys"}},
-		{"rm", "delete", nil, []string{"sys"}},
-		{"run", "run --rm --restart=Never --image-pull-policy=IfNotPresent -i -t", nil, nil},
-	}
-}
-
-func generateResources() []Part {
-	return []Part{
-		// base k8s
-		{"po", "pods", []string{"g", "d", "rm"}, nil},
-		{"dep", "deployment", []string{"g", "d", "rm"}, nil},
-		{"sts", "statefulset", []string{"g", "d", "rm"}, nil},
-		{"svc", "service", []string{"g", "d", "rm"}, nil},
-		{"ing", "ingress", []string{"g", "d", "rm"}, nil},
-		{"cm", "configmap", []string{"g", "d", "rm"}, nil},
-		{"sec", "secret", []string{"g", "d", "rm"}, nil},
-		{"no", "nodes", []string{"g", "d"}, []string{"sys"}},
-		{"ns", "namespaces", []string{"g", "d"}, []string{"sys"}},
-		// istio
-		{"vs", "virtualservices", []string{"g", "d", "rm"}, nil},
-	}
-}
-
-func generateResourceTypes(resources []Part) []string {
-	var resourceTypes []string
-	for _, resource := range resources {
-		resourceTypes = append(resourceTypes, resource.Alias)
-	}
-	return resourceTypes
-}
-
-func generateArguments() []Part {
-	return []Part{
-		{"oyaml", "-o=yaml", []string{"g"}, []string{"owide", "ojson", "sl"}},
-		{"owide", "-o=wide", []string{"g"}, []string{"oyaml", "ojson"}},
-		{"ojson", "-o=json", []string{"g"}, []string{"owide", "oyaml", "sl"}},
-		{"all", "--all-namespaces", []string{"g", "d"}, []string{"rm", "f", "no", "sys"}},
-		{"sl", "--show-labels", []string{"g"}, []string{"oyaml", "ojson"}},
-		{"all", "--all", []string{"rm"}, nil},
-		{"w", "--watch", []string{"g"}, []string{"oyaml", "ojson", "owide"}},
-	}
-}
-
-func generatePositionalArgs(resourceTypes []string) []Part {
-	resourceTypes = append(resourceTypes, []string{"all", "l", "sys"}...)
-	return []Part{
-		{"f", "--recursive -f", []string{"g", "d", "rm"}, resourceTypes},
-		{"l", "-l", []string{"g", "d", "rm"}, []string{"f", "all"}},
-		{"n", "--namespace", []string{"g", "d", "rm", "lo", "ex", "pf"}, []string{"ns", "no", "sys", "all"}},
+		Generator: g,
+		Shell:     aliasShell,
+		Sort:      aliasSort,
+		GroupBy:   aliasGroupBy,
+		Strict:    strictCollisions,
+	}
+	if describeFromKubectl {
+		descriptions, err := describeResources(ag.Resources)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping resource descriptions, %v\n", err)
+		}
+		ag.Descriptions = descriptions
+	}
+	if checkConflicts {
+		existing, err := loadConflicts(conflictSources)
+		if err != nil {
+			return AliasGenerator{}, err
+		}
+		ag.Conflicts = &Conflicts{Existing: existing, Strategy: conflictStrategy, Prefix: conflictPrefix}
+	}
+	if path != "" {
+		if err := ag.Validate(); err != nil {
+			return AliasGenerator{}, fmt.Errorf("config %s:\n%w", path, err)
+		}
+	}
+	return ag, nil
+}
+
+// aliasOptions returns the generator options set by the flags
+func aliasOptions() aliases.Options {
+	opts := aliases.Options{
+		Tools:              aliasTools,
+		Prefix:             aliasPrefix,
+		Bin:                aliasBin,
+		Suffix:             aliasSuffix,
+		RolloutTimeout:     rolloutTimeout,
+		Deprecated:         includeDeprecated,
+		Advanced:           includeAdvanced,
+		Auth:               includeAuth,
+		CertManager:        includeCertManager,
+		VPA:                includeVPA,
+		Include:            includeCategories,
+		Exclude:            excludeCategories,
+		Only:               onlyResources,
+		QualifyGroups:      qualifyGroups,
+		Team:               aliasTeam,
+		NamespaceShortcuts: namespaceShortcuts,
+		DenyVerbs:          denyVerbs,
+		Force:              forceAliases,
+		CombineOps:         combineOps,
+		RBAC:               rbacAliases,
+		Explore:            exploreAliases,
+		Compact:            compactAliases,
+		SpaceFlags:         flagStyle == "space",
+		DeleteWrapper:      deleteWrapper,
+		Normalize:          normalizeAliases,
+	}
+	if verbose {
+		opts.Warnings = os.Stderr
+	}
+	return opts
+}
+
+// render generates the aliases and returns them in the selected format
+func render(ag *AliasGenerator) ([]byte, error) {
+	return renderList(ag, ag.Generate())
+}
+
+// renderList returns the given aliases in the selected format, along with the headers and guards around them
+func renderList(ag *AliasGenerator, aliases []Alias) ([]byte, error) {
+	var out bytes.Buffer
+	ag.Out = &out
+
+	if shellDetectHeader {
+		fmt.Fprint(ag.Out, shellDetectHeaders[aliasFormat])
+	}
+
+	if aliasFormat == "shell" {
+		fmt.Fprintln(ag.Out, comment("Generated aliases for "+ag.shell()))
+	}
+
+	if aliasFormat == "toml" {
+		fmt.Fprintln(ag.Out, "[aliases]")
+	}
+	if ag.Conflicts != nil {
+		aliases = ag.Conflicts.resolve(aliases)
+	}
+	if err := ag.write(aliases); err != nil {
+		return nil, err
+	}
+	if showPruned {
+		ag.Pruned.Write(os.Stderr)
+	}
+	if suggestFixes {
+		for _, suggestion := range ag.SuggestFixes() {
+			fmt.Fprintf(os.Stderr, "suggestion: %s (%s) reuses an alias from its group, %s is unused\n", suggestion.Part.Alias, suggestion.Part.Full, suggestion.Alias)
+		}
+	}
+
+	if checkSyntax {
+		if err := checkShellSyntax(ag.shell(), out.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if !finalNewline {
+		return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+	}
+	return out.Bytes(), nil
+}
+
+// tmuxCommandAlias returns the tmux command alias running the alias's command with run-shell. The command is
+// expanded as a format by run-shell, parsed from double quotes when the alias runs and from single quotes when the
+// config is read, so it's escaped for each in turn, innermost first
+func tmuxCommandAlias(alias Alias) string {
+	command := strings.ReplaceAll(alias.Command, "#", "##")
+	command = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(command)
+	command = strings.ReplaceAll(command, "'", `'\''`)
+	return fmt.Sprintf("set -as command-alias '%s=run-shell \"%s\"'", alias.Name, command)
+}
+
+// tomlString quotes s as a TOML basic string
+func tomlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// shellDetectHeaders holds, for each script format, a preamble that stops sourcing the file in shells
+// that can't run it. POSIX-style shells read sourced files line by line, so the rest of the file is never parsed
+var shellDetectHeaders = map[string]string{
+	"shell":       comment("bash/zsh only") + "\n[ -n \"$BASH_VERSION\" ] || [ -n \"$ZSH_VERSION\" ] || return 0 2>/dev/null\n",
+	"assoc-array": comment("bash 4+/zsh only") + "\n[ -n \"$ZSH_VERSION\" ] || [ \"${BASH_VERSINFO:-0}\" -ge 4 ] || return 0 2>/dev/null\n",
+	"zsh-abbr":    comment("zsh only") + "\n[ -n \"$ZSH_VERSION\" ] || return 0 2>/dev/null\n",
+}
+
+// binaryCheck returns a condition for the shell that succeeds when every command's binary is on the PATH, checking
+// each binary once, or "" when no command has one. The POSIX form is also valid in fish
+func binaryCheck(shell string, commands []Part) string {
+	var binaries, checks []string
+	for _, command := range commands {
+		fields := strings.Fields(command.Full)
+		if len(fields) == 0 || slices.Contains(binaries, fields[0]) {
+			continue
+		}
+		binary := fields[0]
+		binaries = append(binaries, binary)
+		if shell == "powershell" {
+			checks = append(checks, fmt.Sprintf("(Get-Command %s -ErrorAction SilentlyContinue)", binary))
+			continue
+		}
+		checks = append(checks, fmt.Sprintf("command -v %s >/dev/null 2>&1", binary))
+	}
+	if shell == "powershell" {
+		return strings.Join(checks, " -and ")
+	}
+	return strings.Join(checks, " && ")
+}
+
+// checkShellSyntax parses the generated script with the shell's no-execute mode
+func checkShellSyntax(shell string, script []byte) error {
+	check := exec.Command(shell, "-n")
+	switch shell {
+	case "fish":
+		check = exec.Command(shell, "--no-execute")
+	case "powershell":
+		check = exec.Command("pwsh", "-NoProfile", "-NonInteractive", "-Command",
+			"$errors = $null; [void][System.Management.Automation.Language.Parser]::ParseInput([Console]::In.ReadToEnd(), [ref]$null, [ref]$errors); if ($errors) { $errors; exit 1 }")
+	}
+	check.Stdin = bytes.NewReader(script)
+	if output, err := check.CombinedOutput(); err != nil {
+		return fmt.Errorf("generated aliases failed the syntax check: %v\n%s", err, output)
 	}
+	return nil
 }
@@ -0,0 +1,797 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// withFlag sets the flag variable to value for the rest of the test
+func withFlag[T any](t *testing.T, flag *T, value T) {
+	t.Helper()
+	previous := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = previous })
+}
+
+// generate returns the built-in aliases rendered with the flags as currently set
+func generate(t *testing.T) string {
+	t.Helper()
+	ag, err := buildAliasGenerator(Config{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := render(&ag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// lines splits the output into its lines, without the trailing newline
+func lines(out string) []string {
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
+
+// renderAlias returns the alias as writeAlias renders it in the format for the shell
+func renderAlias(t *testing.T, format, shell string, alias Alias) string {
+	t.Helper()
+	previous := aliasFormat
+	aliasFormat = format
+	t.Cleanup(func() { aliasFormat = previous })
+
+	var out bytes.Buffer
+	ag := AliasGenerator{Out: &out, Shell: shell}
+	ag.writeAlias(alias)
+	return out.String()
+}
+
+// hostileCommand breaks, or runs code from, any line that doesn't quote it
+const hostileCommand = `kubectl get pods -l "app=$(whoami)" -o=jsonpath='{.items[*]}' ` + "`id`" + ` \ #`
+
+func TestTmuxCommandAlias(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"kubectl get pods", `set -as command-alias 'kgpo=run-shell "kubectl get pods"'`},
+		{`kubectl get pods -o=jsonpath='{.items[*].metadata.name}'`, `set -as command-alias 'kgpo=run-shell "kubectl get pods -o=jsonpath='\''{.items[*].metadata.name}'\''"'`},
+		{`kubectl get pods -l "app=$APP"`, `set -as command-alias 'kgpo=run-shell "kubectl get pods -l \"app=\$APP\""'`},
+		{`kubectl get pods #{pane_id} a\b`, `set -as command-alias 'kgpo=run-shell "kubectl get pods ##{pane_id} a\\b"'`},
+	}
+	for _, test := range tests {
+		if got := tmuxCommandAlias(Alias{Name: "kgpo", Command: test.command}); got != test.want {
+			t.Errorf("%s:\n got %s\nwant %s", test.command, got, test.want)
+		}
+	}
+}
+
+func TestZshAbbr(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"kubectl get pods", "abbr kgpo='kubectl get pods'\n"},
+		{`kubectl get pods -o=jsonpath='{.items}'`, `abbr kgpo='kubectl get pods -o=jsonpath='\''{.items}'\'''` + "\n"},
+		{`kubectl get "$POD"`, `abbr kgpo='kubectl get "$POD"'` + "\n"},
+	}
+	for _, test := range tests {
+		if got := renderAlias(t, "zsh-abbr", "zsh", Alias{Name: "kgpo", Command: test.command}); got != test.want {
+			t.Errorf("%s:\n got %s\nwant %s", test.command, got, test.want)
+		}
+	}
+}
+
+func TestFormatShellError(t *testing.T) {
+	tests := []struct {
+		format string
+		shell  string
+		err    bool
+	}{
+		{"zsh-abbr", "zsh", false},
+		// A bash rc file sourcing abbr lines fails in every new shell
+		{"zsh-abbr", "bash", true},
+		{"zsh-abbr", "fish", true},
+		{"zsh-abbr", "powershell", true},
+		{"fish-abbr", "fish", false},
+		{"fish-abbr", "bash", true},
+		{"assoc-array", "bash", false},
+		{"assoc-array", "fish", true},
+		{"shell", "powershell", false},
+		{"toml", "fish", false},
+	}
+	for _, test := range tests {
+		if err := formatShellError(test.format, test.shell); (err != nil) != test.err {
+			t.Errorf("--format %s --shell %s: got error %v, want one %v", test.format, test.shell, err, test.err)
+		}
+	}
+
+	// The flags are checked before any alias is generated
+	withFlag(t, &aliasFormat, "zsh-abbr")
+	withFlag(t, &aliasShell, "bash")
+	if err := aliasesCmd.PersistentPreRunE(aliasesCmd, nil); err == nil || !strings.Contains(err.Error(), "requires --shell zsh") {
+		t.Errorf("--format zsh-abbr with --shell bash: got %v", err)
+	}
+}
+
+func TestZshAbbrKeepsCommandWhenSourced(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash isn't installed")
+	}
+	// zsh and bash read single-quoted words the same way, so bash stands in for zsh with abbr stubbed out
+	line := renderAlias(t, "zsh-abbr", "zsh", Alias{Name: "kgpo", Command: hostileCommand})
+	output, err := exec.Command("bash", "-c", `abbr() { printf '%s' "$1"; }`+"\n"+line).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sourcing %s failed: %v\n%s", line, err, output)
+	}
+	if want := "kgpo=" + hostileCommand; string(output) != want {
+		t.Errorf("sourcing %s defined\n %s\nwant %s", line, output, want)
+	}
+}
+
+func TestCertManagerFlag(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		previous := includeCertManager
+		includeCertManager = enabled
+		ag, err := buildAliasGenerator(Config{}, "")
+		includeCertManager = previous
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := aliases.ResourceTypes(aliases.FilterCategories(ag.Resources, []string{"cert-manager"}, nil))
+		want := []string{"cert", "iss", "ciss"}
+		if !enabled {
+			want = nil
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("with --cert-manager=%v the cert-manager resources are %v, want %v", enabled, got, want)
+		}
+	}
+}
+
+func TestTomlString(t *testing.T) {
+	tests := map[string]string{
+		"kubectl get pods":             `"kubectl get pods"`,
+		`kubectl get pods -l "app=x"`:  `"kubectl get pods -l \"app=x\""`,
+		`kubectl exec pod -- echo a\b`: `"kubectl exec pod -- echo a\\b"`,
+		`kubectl get '{.items}'`:       `"kubectl get '{.items}'"`,
+	}
+	for s, want := range tests {
+		if got := tomlString(s); got != want {
+			t.Errorf("tomlString(%s) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestShellDefinition(t *testing.T) {
+	tests := []struct {
+		shell string
+		alias Alias
+		want  string
+	}{
+		{"bash", Alias{Name: "kgpo", Command: "kubectl get pods"}, `alias kgpo='kubectl get pods'`},
+		{"zsh", Alias{Name: "kgpo", Command: `kubectl get pods -o=jsonpath='{.items}'`}, `alias kgpo='kubectl get pods -o=jsonpath='\''{.items}'\'''`},
+		{"bash", Alias{Name: "krm", Command: "confirm kubectl delete", Function: true}, `krm() { confirm kubectl delete "$@"; }`},
+		{"fish", Alias{Name: "kgpo", Command: `kubectl get pods -o=jsonpath='{.items}' a\b`}, `alias kgpo 'kubectl get pods -o=jsonpath=\'{.items}\' a\\b'`},
+		{"fish", Alias{Name: "krm", Command: "confirm kubectl delete", Function: true}, `function krm; confirm kubectl delete $argv; end`},
+		{"powershell", Alias{Name: "kgpo", Command: "kubectl get pods"}, `function kgpo { kubectl get pods @args }`},
+	}
+	for _, test := range tests {
+		if got := shellDefinition(test.shell, test.alias); got != test.want {
+			t.Errorf("%s %s:\n got %s\nwant %s", test.shell, test.alias.Name, got, test.want)
+		}
+	}
+}
+
+func TestShellAliasKeepsCommandWhenSourced(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash isn't installed")
+	}
+	for _, format := range []string{"shell", "assoc-array"} {
+		line := renderAlias(t, format, "bash", Alias{Name: "kgpo", Command: hostileCommand})
+		script := line + "\nprintf '%s' \"${BASH_ALIASES[kgpo]}\""
+		if format == "assoc-array" {
+			script = "declare -A KUBE_ALIASES=(\n" + line + ")\nprintf '%s' \"${KUBE_ALIASES[kgpo]}\""
+		}
+		output, err := exec.Command("bash", "-c", script).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: sourcing %s failed: %v\n%s", format, line, err, output)
+		}
+		if string(output) != hostileCommand {
+			t.Errorf("%s: sourcing %s defined\n %s\nwant %s", format, line, output, hostileCommand)
+		}
+	}
+}
+
+func TestFzfFormat(t *testing.T) {
+	withFlag(t, &aliasFormat, "fzf")
+	got := lines(generate(t))
+	if !slices.Contains(got, "kgpo\tkubectl get pods") {
+		t.Errorf("fzf output doesn't contain kgpo<TAB>kubectl get pods")
+	}
+	// Every line, with no header or comments, is an alias name and its command split by a single tab
+	for _, line := range got {
+		name, command, ok := strings.Cut(line, "\t")
+		if !ok || name == "" || !aliasNamePattern.MatchString(name) || strings.Contains(command, "\t") || !strings.HasPrefix(command, "kubectl") {
+			t.Errorf("line %q isn't alias<TAB>command", line)
+		}
+	}
+}
+
+func TestIncludeDeprecated(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		withFlag(t, &includeDeprecated, enabled)
+		out := generate(t)
+		for _, line := range []string{
+			"alias kgpsp='kubectl get podsecuritypolicies' # deprecated",
+			"alias kdep='kubectl describe endpoints' # deprecated",
+		} {
+			if got := slices.Contains(lines(out), line); got != enabled {
+				t.Errorf("with --include-deprecated=%v the output contains %s: %v", enabled, line, got)
+			}
+		}
+		if !enabled && strings.Contains(out, "deprecated") {
+			t.Errorf("without --include-deprecated the output mentions deprecated resources")
+		}
+	}
+}
+
+func TestCheckSyntax(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash isn't installed")
+	}
+	path := filepath.Join(t.TempDir(), "aliases.yaml")
+	config := "ops:\n  - alias: lg\n    full: logs --since='1h' -l \"app=$APP\"\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag, err := buildAliasGenerator(*loaded, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFlag(t, &checkSyntax, true)
+	for _, format := range []string{"shell", "assoc-array"} {
+		withFlag(t, &aliasFormat, format)
+		if _, err := render(&ag); err != nil {
+			t.Errorf("%s: quoted config expansion failed the syntax check: %v", format, err)
+		}
+	}
+
+	// An expansion with a quote that isn't escaped is exactly what the check is for
+	broken := "alias klg='kubectl logs --since='1h' -l \"app=$APP\"\n"
+	if err := checkShellSyntax("bash", []byte(broken)); err == nil {
+		t.Errorf("unbalanced quotes passed the syntax check")
+	}
+}
+
+func TestBinaryCheck(t *testing.T) {
+	commands := []Part{{Alias: "k", Full: "kubectl"}, {Alias: "kc", Full: "kubectl --context=dev"}, {Alias: "x", Full: " "}, {Alias: "h", Full: "helm"}}
+	tests := []struct {
+		shell    string
+		commands []Part
+		want     string
+	}{
+		{"bash", commands, "command -v kubectl >/dev/null 2>&1 && command -v helm >/dev/null 2>&1"},
+		{"fish", commands[:1], "command -v kubectl >/dev/null 2>&1"},
+		{"powershell", commands, "(Get-Command kubectl -ErrorAction SilentlyContinue) -and (Get-Command helm -ErrorAction SilentlyContinue)"},
+		{"bash", []Part{{Alias: "x", Full: ""}}, ""},
+		{"bash", nil, ""},
+	}
+	for _, test := range tests {
+		if got := binaryCheck(test.shell, test.commands); got != test.want {
+			t.Errorf("%s %v:\n got %s\nwant %s", test.shell, test.commands, got, test.want)
+		}
+	}
+}
+
+func TestGuardBinary(t *testing.T) {
+	withFlag(t, &guardBinary, true)
+	tests := []struct {
+		shell string
+		open  string
+		close string
+	}{
+		{"bash", "if command -v kubectl >/dev/null 2>&1; then", "fi"},
+		{"zsh", "if command -v kubectl >/dev/null 2>&1; then", "fi"},
+		{"fish", "if command -v kubectl >/dev/null 2>&1", "end"},
+		{"powershell", "if (Get-Command kubectl -ErrorAction SilentlyContinue) {", "}"},
+	}
+	for _, test := range tests {
+		withFlag(t, &aliasShell, test.shell)
+		got := lines(generate(t))
+		// The guard opens after the generated comment and closes after the last alias
+		if got[1] != test.open || got[len(got)-1] != test.close {
+			t.Errorf("%s: aliases are guarded by %q ... %q, want %q ... %q", test.shell, got[1], got[len(got)-1], test.open, test.close)
+		}
+	}
+
+	// Without any tools there are no commands, so there is nothing to check or guard
+	withFlag(t, &aliasShell, "bash")
+	withFlag(t, &aliasTools, []string{})
+	if got := generate(t); strings.Contains(got, "if ") || strings.Contains(got, "fi\n") {
+		t.Errorf("output without commands is still guarded:\n%s", got)
+	}
+}
+
+func TestGuardBinaryPerTool(t *testing.T) {
+	withFlag(t, &guardBinary, true)
+	withFlag(t, &aliasTools, []string{"kubectl", "helm"})
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash isn't installed")
+	}
+	// Only kubectl is installed, so the helm aliases are dropped while the kubectl ones are still defined
+	bin := t.TempDir()
+	writeExecutable(t, filepath.Join(bin, "kubectl"), 0o755)
+	tests := []struct {
+		format string
+		check  string
+	}{
+		{"shell", "alias kgpo && ! alias hls"},
+		{"assoc-array", `[ "${KUBE_ALIASES[kgpo]}" = 'kubectl get pods' ] && [ -z "${KUBE_ALIASES[hls]:-}" ]`},
+	}
+	for _, test := range tests {
+		withFlag(t, &aliasFormat, test.format)
+		got := generate(t)
+		for _, guard := range []string{"if command -v kubectl >/dev/null 2>&1; then", "if command -v helm >/dev/null 2>&1; then"} {
+			if strings.Count(got, guard) != 1 {
+				t.Errorf("%s: want one %q guard:\n%s", test.format, guard, got)
+			}
+		}
+		script := exec.Command(bash, "-c", got+test.check)
+		script.Env = []string{"PATH=" + bin}
+		if output, err := script.CombinedOutput(); err != nil {
+			t.Errorf("%s: %s failed with only kubectl installed: %v\n%s", test.format, test.check, err, output)
+		}
+	}
+}
+
+func TestAssocArrayFormat(t *testing.T) {
+	withFlag(t, &aliasFormat, "assoc-array")
+	for _, shell := range []string{"bash", "zsh"} {
+		withFlag(t, &aliasShell, shell)
+		got := lines(generate(t))
+		if got[0] != "declare -A KUBE_ALIASES=(" || got[len(got)-1] != ")" {
+			t.Errorf("%s: the aliases aren't declared as one associative array:\n%s\n...\n%s", shell, got[0], got[len(got)-1])
+		}
+		if !slices.Contains(got, "  [kgpo]='kubectl get pods'") {
+			t.Errorf("%s: the array doesn't contain [kgpo]='kubectl get pods'", shell)
+		}
+		for _, line := range got[1 : len(got)-1] {
+			if !strings.HasPrefix(line, "  [") || !strings.Contains(line, "]='") {
+				t.Errorf("%s: %q isn't an array entry", shell, line)
+			}
+		}
+
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		script := strings.Join(got, "\n") + "\nprintf '%s' \"${KUBE_ALIASES[kgpo]}\""
+		output, err := exec.Command(shell, "-c", script).CombinedOutput()
+		if err != nil || string(output) != "kubectl get pods" {
+			t.Errorf("%s: sourcing the array gave %q, %v", shell, output, err)
+		}
+	}
+}
+
+func TestCommentsAreValidForEachShell(t *testing.T) {
+	// Grouped sections, deprecated resources and resource descriptions are all commented
+	withFlag(t, &aliasGroupBy, "resource")
+	withFlag(t, &includeDeprecated, true)
+	binaries := map[string]string{"bash": "bash", "zsh": "zsh", "fish": "fish", "powershell": "pwsh"}
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		withFlag(t, &aliasShell, shell)
+		ag, err := buildAliasGenerator(Config{}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ag.Descriptions = map[string]string{"pods": "Pod is a collection of containers"}
+		out, err := render(&ag)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		comments := 0
+		for _, line := range lines(string(out)) {
+			if before, text, found := strings.Cut(line, "#"); found {
+				// A comment either fills the line or follows a complete definition
+				if !strings.HasPrefix(text, " ") || (before != "" && !strings.HasSuffix(before, " ")) {
+					t.Errorf("%s: %q isn't a valid comment", shell, line)
+				}
+				comments++
+			}
+		}
+		if !strings.Contains(string(out), comment("pods: Pod is a collection of containers")) || comments < 3 {
+			t.Errorf("%s: expected grouped, deprecated and description comments, got %d", shell, comments)
+		}
+		if _, err := exec.LookPath(binaries[shell]); err == nil {
+			if err := checkShellSyntax(shell, out); err != nil {
+				t.Errorf("%s: %v", shell, err)
+			}
+		}
+	}
+}
+
+func TestOutputPerm(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for perm, want := range map[string]os.FileMode{"0644": 0o644, "0600": 0o600, "755": 0o755} {
+		path := filepath.Join(t.TempDir(), "aliases.sh")
+		withFlag(t, &outputPath, path)
+		withFlag(t, &outputPerm, perm)
+		if err := runAliases(); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("with --output-perm %s the file has mode %o, want %o", perm, got, want)
+		}
+	}
+	for _, perm := range []string{"", "rw-r--r--", "0o644", "0888", "1777", "-644"} {
+		withFlag(t, &outputPerm, perm)
+		if _, err := outputFileMode(); err == nil {
+			t.Errorf("--output-perm %q is accepted", perm)
+		}
+	}
+}
+
+func TestSortAliases(t *testing.T) {
+	generated := []Alias{
+		{Name: "kgpo", Command: "kubectl get pods"},
+		{Name: "kg", Command: "kubectl get"},
+		{Name: "krmpo", Command: "kubectl delete pods"},
+		{Name: "kdpo", Command: "kubectl describe pods"},
+		{Name: "k", Command: "kubectl"},
+		{Name: "kd", Command: "kubectl describe"},
+	}
+	tests := map[string][]string{
+		"length":  {"k", "kd", "kg", "kdpo", "kgpo", "krmpo"},
+		"name":    {"k", "kd", "kdpo", "kg", "kgpo", "krmpo"},
+		"command": {"k", "krmpo", "kd", "kdpo", "kg", "kgpo"},
+	}
+	for mode, want := range tests {
+		sorted := slices.Clone(generated)
+		sortAliases(sorted, aliasSorts[mode])
+		var got []string
+		for _, alias := range sorted {
+			got = append(got, alias.Name)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("--sort %s: got %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	generated := []Alias{
+		{Name: "k", Command: "kubectl"},
+		{Name: "kgpo", Command: "kubectl get pods", Operation: "get", Resource: "pods"},
+		{Name: "kgpooyaml", Command: "kubectl get pods -o=yaml", Operation: "get", Resource: "pods", Argument: "-o=yaml"},
+		{Name: "krmdep", Command: "kubectl delete deployment", Operation: "delete", Resource: "deployment"},
+		{Name: "kgdepoyaml", Command: "kubectl get deployment -o=yaml", Operation: "get", Resource: "deployment", Argument: "-o=yaml"},
+	}
+	// Sections follow their first alias, with the aliases that have no part in the group last
+	tests := map[string][]string{
+		"operation": {"# get", "kgpo", "kgpooyaml", "kgdepoyaml", "", "# delete", "krmdep", "", "# no operation", "k"},
+		"resource":  {"# pods", "kgpo", "kgpooyaml", "", "# deployment", "krmdep", "kgdepoyaml", "", "# no resource", "k"},
+		"argument":  {"# -o=yaml", "kgpooyaml", "kgdepoyaml", "", "# no argument", "k", "kgpo", "krmdep"},
+	}
+	withFlag(t, &aliasFormat, "shell")
+	for dimension, want := range tests {
+		var out bytes.Buffer
+		ag := AliasGenerator{Out: &out, GroupBy: dimension}
+		ag.writeGrouped(generated)
+		var got []string
+		for _, line := range lines(out.String()) {
+			if definition, found := strings.CutPrefix(line, "alias "); found {
+				line, _, _ = strings.Cut(definition, "=")
+			}
+			got = append(got, line)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("--group-by %s: got %q, want %q", dimension, got, want)
+		}
+	}
+}
+
+func TestRolloutTimeout(t *testing.T) {
+	tests := []struct {
+		timeout string
+		want    string
+	}{
+		{timeout: aliases.DefaultRolloutTimeout, want: "kubectl rollout status --timeout=300s deployment"},
+		{timeout: "1m30s", want: "kubectl rollout status --timeout=1m30s deployment"},
+		{timeout: "", want: "kubectl rollout status deployment"},
+	}
+	for _, test := range tests {
+		withFlag(t, &rolloutTimeout, test.timeout)
+		out := generate(t)
+		if want := "alias krstdep='" + test.want + "'"; !slices.Contains(lines(out), want) {
+			t.Errorf("--rollout-timeout=%q: no %s", test.timeout, want)
+		}
+		for _, line := range lines(out) {
+			wantTimeout := test.timeout != "" && strings.Contains(line, "rollout status")
+			if strings.Contains(line, "rollout status --timeout="+test.timeout) != wantTimeout || strings.Contains(line, "--timeout") != wantTimeout {
+				t.Errorf("--rollout-timeout=%q: timeout on the wrong aliases: %s", test.timeout, line)
+			}
+		}
+	}
+}
+
+func TestRBACAliases(t *testing.T) {
+	want := []string{
+		"alias kcani='kubectl auth can-i'",
+		"alias kcanil='kubectl auth can-i --list'",
+		"alias kcanias='kubectl auth can-i --as'",
+	}
+	for _, enabled := range []bool{false, true} {
+		withFlag(t, &rbacAliases, enabled)
+		var got []string
+		for _, line := range lines(generate(t)) {
+			if strings.Contains(line, "auth can-i") {
+				got = append(got, line)
+			}
+		}
+		if !enabled && len(got) > 0 {
+			t.Errorf("--rbac-aliases=false: unexpected %q", got)
+		}
+		if enabled && !slices.Equal(got, want) {
+			t.Errorf("--rbac-aliases: got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestExploreAliases(t *testing.T) {
+	want := []string{
+		"alias kar='kubectl api-resources'",
+		"alias karo='kubectl api-resources -o=wide'",
+		"alias karn='kubectl api-resources --namespaced=true'",
+	}
+	for _, enabled := range []bool{false, true} {
+		withFlag(t, &exploreAliases, enabled)
+		var got []string
+		for _, line := range lines(generate(t)) {
+			if strings.Contains(line, "api-resources") {
+				got = append(got, line)
+			}
+		}
+		if !enabled && len(got) > 0 {
+			t.Errorf("--explore-aliases=false: unexpected %q", got)
+		}
+		if enabled && !slices.Equal(got, want) {
+			t.Errorf("--explore-aliases: got %q, want %q", got, want)
+		}
+	}
+
+	// None of the names is taken by another alias, even with every opt-in group added
+	withFlag(t, &includeCertManager, true)
+	withFlag(t, &includeAdvanced, true)
+	withFlag(t, &rbacAliases, true)
+	ag, err := buildAliasGenerator(Config{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, commands := range aliases.FindCollisions(ag.Generate()) {
+		if slices.ContainsFunc(commands, func(command string) bool { return strings.Contains(command, "api-resources") }) {
+			t.Errorf("%s collides: %q", name, commands)
+		}
+	}
+}
+
+func TestShellDetectHeader(t *testing.T) {
+	for _, shell := range []string{"bash", "dash"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			t.Skipf("%s isn't installed", shell)
+		}
+	}
+	withFlag(t, &shellDetectHeader, true)
+	for _, format := range []string{"shell", "assoc-array"} {
+		withFlag(t, &aliasFormat, format)
+		out := generate(t)
+		if !strings.HasPrefix(out, shellDetectHeaders[format]) {
+			t.Errorf("%s: output doesn't start with the header:\n%s", format, out[:min(len(out), 200)])
+		}
+
+		path := filepath.Join(t.TempDir(), "aliases.sh")
+		if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// bash defines the aliases, while dash stops at the header before the syntax it can't parse
+		check := ". " + path + " && { alias kgpo >/dev/null 2>&1 || [ -n \"${KUBE_ALIASES[kgpo]:-}\" ]; }"
+		if err := exec.Command("bash", "-c", check).Run(); err != nil {
+			t.Errorf("%s: bash didn't define kgpo: %v", format, err)
+		}
+		output, err := exec.Command("dash", "-c", ". "+path+" && echo sourced; alias kgpo >/dev/null 2>&1 && echo defined").CombinedOutput()
+		if string(output) != "sourced\n" {
+			t.Errorf("%s: dash didn't skip the file: %v\n%s", format, err, output)
+		}
+	}
+}
+
+func TestForceAliases(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		withFlag(t, &forceAliases, enabled)
+		var forced []string
+		for _, line := range lines(generate(t)) {
+			if strings.Contains(line, "--force") {
+				forced = append(forced, line)
+			}
+		}
+		want := []string(nil)
+		if enabled {
+			want = []string{
+				"alias ksysrepforce='kubectl --namespace=kube-system replace --force -f'",
+				"alias krepforce='kubectl replace --force -f'",
+			}
+		}
+		if !slices.Equal(forced, want) {
+			t.Errorf("--force-aliases=%v: got %q, want %q", enabled, forced, want)
+		}
+	}
+}
+
+func TestFinalNewline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, format := range []string{"shell", "fzf", "toml", "json", "markdown"} {
+		withFlag(t, &aliasFormat, format)
+		withFlag(t, &finalNewline, true)
+		with := generate(t)
+		if !strings.HasSuffix(with, "\n") || strings.HasSuffix(with, "\n\n") {
+			t.Errorf("%s: --final-newline=true doesn't end with exactly one newline: %q", format, with[max(0, len(with)-20):])
+		}
+
+		// The file written with --output ends the same way as the output itself
+		withFlag(t, &finalNewline, false)
+		withFlag(t, &outputPath, filepath.Join(t.TempDir(), "aliases"))
+		if err := runAliases(); err != nil {
+			t.Fatal(err)
+		}
+		written, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		without := generate(t)
+		if without != strings.TrimSuffix(with, "\n") || !strings.HasSuffix(string(written), without) {
+			t.Errorf("%s: --final-newline=false doesn't only drop the trailing newline: %q", format, without[max(0, len(without)-20):])
+		}
+	}
+}
+
+func TestCountOnly(t *testing.T) {
+	// Without a config file the count is of the built-in aliases, once each
+	t.Setenv("HOME", t.TempDir())
+	withFlag(t, &countOnly, true)
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFlag(t, &os.Stdout, writer)
+	runErr := runAliases()
+	writer.Close()
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	ag, err := buildAliasGenerator(Config{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("%d\n", len(aliases.Dedup(ag.Generate()))); string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestSuffix(t *testing.T) {
+	withFlag(t, &aliasSuffix, "2")
+	withFlag(t, &rbacAliases, true)
+	definitions := parseDefinitions(generate(t))
+	if len(definitions) == 0 {
+		t.Fatal("no aliases generated")
+	}
+	for name := range definitions {
+		if !strings.HasSuffix(name, "2") {
+			t.Errorf("%s has no suffix", name)
+		}
+	}
+	if want := "alias kgpo2='kubectl get pods'"; definitions["kgpo2"] != want {
+		t.Errorf("got %q, want %q", definitions["kgpo2"], want)
+	}
+
+	// Collisions are found between the suffixed names
+	withFlag(t, &strictCollisions, true)
+	ag, err := buildAliasGenerator(Config{Convenience: []Part{{Alias: "gpo", Full: "get pods -o=wide"}}}, "collide.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := render(&ag); err == nil || !strings.Contains(err.Error(), "kgpo2") {
+		t.Errorf("got %v, want a collision on kgpo2", err)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	withFlag(t, &aliasPrefix, "kc")
+	withFlag(t, &aliasBin, "kubecolor")
+	withFlag(t, &namespaceShortcuts, []string{"mon=monitoring"})
+	definitions := parseDefinitions(generate(t))
+	for name, definition := range definitions {
+		if !strings.HasPrefix(name, "kc") || !strings.Contains(definition, "'kubecolor") {
+			t.Errorf("%s doesn't start with the prefix and run the binary: %s", name, definition)
+		}
+	}
+
+	// The global ops still combine after the prefix
+	want := map[string]string{
+		"kcgpo":    "alias kcgpo='kubecolor get pods'",
+		"kcsysgpo": "alias kcsysgpo='kubecolor --namespace=kube-system get pods'",
+		"kcmongpo": "alias kcmongpo='kubecolor --namespace=monitoring get pods'",
+	}
+	for name, definition := range want {
+		if definitions[name] != definition {
+			t.Errorf("got %q, want %q", definitions[name], definition)
+		}
+	}
+}
+
+func TestDenyVerbsReachesTools(t *testing.T) {
+	withFlag(t, &aliasTools, []string{"kubectl", "helm"})
+	withFlag(t, &denyVerbs, []string{"uninstall", "delete"})
+	definitions := parseDefinitions(generate(t))
+	for _, denied := range []string{"hun", "hunn", "hundry", "krmpo"} {
+		if definition, ok := definitions[denied]; ok {
+			t.Errorf("%s is still generated: %s", denied, definition)
+		}
+	}
+	for _, kept := range []string{"hls", "hin", "kgpo"} {
+		if _, ok := definitions[kept]; !ok {
+			t.Errorf("%s is no longer generated", kept)
+		}
+	}
+}
+
+func TestCategoryFlags(t *testing.T) {
+	tests := []struct {
+		include []string
+		exclude []string
+		err     string
+	}{
+		{include: []string{"core"}},
+		{exclude: []string{"istio"}},
+		{include: []string{"cert-manger"}, err: `unknown category "cert-manger", expected one of core, istio`},
+		{exclude: []string{"core", "istoi"}, err: `unknown category "istoi", expected one of core, istio`},
+	}
+	for _, test := range tests {
+		withFlag(t, &includeCategories, test.include)
+		withFlag(t, &excludeCategories, test.exclude)
+		ag, err := buildAliasGenerator(Config{}, "")
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("--include %v --exclude %v: got error %v, want %q", test.include, test.exclude, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ag.Resources) == 0 {
+			t.Errorf("--include %v --exclude %v: no resources left", test.include, test.exclude)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	aliasesCmd.AddCommand(browseCmd)
+}
+
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Searches the aliases interactively with fzf and exports the selected ones",
+	Long: "Opens the generated aliases in fzf, matching on both the alias and the command it expands to and previewing" +
+		"\nthe full command. The aliases selected with TAB are written to stdout in the selected format when you press" +
+		"\nENTER, e.g. kt aliases browse >> ~/.kube_aliases. Requires fzf on the PATH.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ag, err := newAliasGenerator()
+		if err != nil {
+			return err
+		}
+		aliases := ag.Generate()
+		selected, err := pickAliases(aliases)
+		if err != nil || len(selected) == 0 {
+			return err
+		}
+		out, err := renderList(&ag, selected)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+// pickAliases lets the user choose aliases in fzf, returning none if the picker was cancelled
+func pickAliases(aliases []Alias) ([]Alias, error) {
+	if _, err := exec.LookPath("fzf"); err != nil {
+		return nil, fmt.Errorf("browse needs fzf on the PATH, see https://github.com/junegunn/fzf")
+	}
+
+	var list strings.Builder
+	for _, alias := range aliases {
+		fmt.Fprintf(&list, "%s\t%s\n", alias.Name, alias.Command)
+	}
+	output, err := runFzf(list.String(), "--multi", "--delimiter=\t", "--tabstop=24",
+		"--prompt=alias> ", "--header=TAB selects, ENTER exports the selection",
+		"--preview=echo {2..}", "--preview-window=down,3,wrap")
+	if err != nil {
+		return nil, err
+	}
+	return selectedAliases(output, aliases), nil
+}
+
+// selectedAliases returns the aliases named by the name<TAB>command lines fzf printed, in the order they were
+// selected
+func selectedAliases(output string, aliases []Alias) []Alias {
+	byName := make(map[string]Alias, len(aliases))
+	for _, alias := range aliases {
+		byName[alias.Name] = alias
+	}
+	var selected []Alias
+	for _, line := range strings.Split(output, "\n") {
+		name, _, _ := strings.Cut(line, "\t")
+		if alias, ok := byName[name]; ok {
+			selected = append(selected, alias)
+		}
+	}
+	return selected
+}
+
+// runFzf runs fzf with the arguments on input and returns the selected lines, or "" if nothing matched or the
+// picker was cancelled
+func runFzf(input string, args ...string) (string, error) {
+	picker := exec.Command("fzf", args...)
+	picker.Stdin = strings.NewReader(input)
+	picker.Stderr = os.Stderr
+	output, err := picker.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// fzf exits with 1 when nothing matched and 130 when cancelled
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+			return "", nil
+		}
+		return "", fmt.Errorf("fzf failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectedAliases(t *testing.T) {
+	generated := []Alias{
+		{Name: "kgpo", Command: "kubectl get pods"},
+		{Name: "kgpooyaml", Command: "kubectl get pods -o=yaml"},
+		{Name: "kl", Command: "kubectl logs -f\tkubectl"},
+	}
+	tests := []struct {
+		name   string
+		output string
+		want   []Alias
+	}{
+		{"nothing selected", "", nil},
+		{"single selection", "kgpo\tkubectl get pods", generated[:1]},
+		{"selection order is kept", "kgpooyaml\tkubectl get pods -o=yaml\nkgpo\tkubectl get pods", []Alias{generated[1], generated[0]}},
+		// Only the name before the first tab picks the alias, whatever the command holds
+		{"command with a tab", "kl\tkubectl logs -f\tkubectl", generated[2:]},
+		{"name that's only a prefix", "kg\tkubectl get", nil},
+		{"line without a command", "kgpo", generated[:1]},
+		{"unknown names are skipped", "knope\tkubectl nope\nkgpo\tkubectl get pods", generated[:1]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := selectedAliases(test.output, generated); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunFzf(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    string
+		wantErr bool
+	}{
+		{"selection", "head -n 1\n", "api-1", false},
+		{"nothing matched", "exit 1\n", "", false},
+		{"cancelled", "exit 130\n", "", false},
+		{"failed", "echo broken >&2\nexit 2\n", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "fzf"), []byte("#!/bin/sh\n"+test.script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			got, err := runFzf("api-1\napi-2\n", "--prompt=pod> ")
+			if (err != nil) != test.wantErr || (err != nil && !strings.Contains(err.Error(), "fzf failed")) {
+				t.Fatalf("got error %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// cleanupKinds are the resources fetched for the scanners, both the candidates and the ones that reference them
+var cleanupKinds = []string{"pods", "jobs", "cronjobs", "replicasets", "deployments", "statefulsets", "daemonsets",
+	"configmaps", "secrets", "serviceaccounts", "ingresses"}
+
+// cleanupIgnoredSecretTypes are secret types managed by the cluster or by tools that never mount them in a pod
+var cleanupIgnoredSecretTypes = []string{"kubernetes.io/service-account-token", "bootstrap.kubernetes.io/token", "helm.sh/release.v1"}
+
+// cleanupIgnoredConfigMaps are config maps published into every namespace for pods that may be created later
+var cleanupIgnoredConfigMaps = []string{"kube-root-ca.crt", "istio-ca-root-cert"}
+
+// cleanupConfigKinds are the kinds only cleaned up with --unreferenced-config, as controllers read some of them by
+// name through the API or their flags rather than through a reference the scanners can see
+var cleanupConfigKinds = []string{"ConfigMap", "Secret"}
+
+// cleanupSystemNamespacePrefix marks the namespaces of the cluster's own components, such as kube-system, whose
+// resources are often read by controllers rather than referenced by a pod
+const cleanupSystemNamespacePrefix = "kube-"
+
+var (
+	// cleanupNamespace is the namespace to scan, defaulting to the current context's
+	cleanupNamespace string
+	// cleanupAllNamespaces scans every namespace
+	cleanupAllNamespaces bool
+	// cleanupJobDays is how many days a job has to have been failed for to be cleaned up
+	cleanupJobDays int
+	// cleanupDryRun lists what would be deleted without deleting it
+	cleanupDryRun bool
+	// cleanupYes deletes without asking for confirmation
+	cleanupYes bool
+	// cleanupIncludeSystem also cleans up the kube-* namespaces
+	cleanupIncludeSystem bool
+	// cleanupUnreferencedConfig also cleans up the config maps and secrets nothing references
+	cleanupUnreferencedConfig bool
+)
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+	cleanupCmd.Flags().StringVarP(&cleanupNamespace, "namespace", "n", "", "Namespace to scan (default the current context's)")
+	cleanupCmd.Flags().BoolVarP(&cleanupAllNamespaces, "all-namespaces", "A", false, "Scan every namespace")
+	cleanupCmd.Flags().IntVar(&cleanupJobDays, "failed-job-days", 7, "Days a job has to have been failed for to be cleaned up")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "List what would be deleted without deleting anything")
+	cleanupCmd.Flags().BoolVarP(&cleanupYes, "yes", "y", false, "Delete without asking for confirmation")
+	cleanupCmd.Flags().BoolVar(&cleanupIncludeSystem, "include-system", false, "Also clean up the kube-* namespaces, which are skipped by default")
+	cleanupCmd.Flags().BoolVar(&cleanupUnreferencedConfig, "unreferenced-config", false, "Also clean up the config maps and secrets no pod, pod template, service account or ingress references")
+}
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Deletes finished pods, old failed jobs and empty replica sets, and optionally unreferenced config",
+	Long: "Scans the namespace, or every namespace, for resources that are safe to delete:" +
+		"\n  - pods that completed or were evicted" +
+		"\n  - jobs that failed more than --failed-job-days ago" +
+		"\n  - replica sets scaled to zero, which are old deployment revisions" +
+		"\n  - with --unreferenced-config, config maps and secrets no pod, pod template, service account or" +
+		"\n    ingress references" +
+		"\nand deletes them after listing them and asking for confirmation. The kube-* namespaces are skipped" +
+		"\nunless --include-system is given." +
+		"\n\nConfig maps and secrets are only scanned with --unreferenced-config, because controllers read some by" +
+		"\nname instead, such as ingress-nginx's --configmap, leader election locks and the secrets named by an" +
+		"\nIstio or Gateway API credentialName. Check the list before deleting them.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cleanupAllNamespaces && cleanupNamespace != "" {
+			return fmt.Errorf("--namespace and --all-namespaces can't be used together")
+		}
+		if isSystemNamespace(cleanupNamespace) && !cleanupIncludeSystem {
+			return fmt.Errorf("%s is a system namespace, add --include-system to clean it up", cleanupNamespace)
+		}
+		objects, err := getCleanupObjects()
+		if err != nil {
+			return err
+		}
+		candidates := scanForCleanup(objects, time.Now())
+		if len(candidates) == 0 {
+			fmt.Println("nothing to clean up")
+			return nil
+		}
+
+		out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(out, "KIND\tNAMESPACE\tNAME\tREASON")
+		for _, candidate := range candidates {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", candidate.Kind, candidate.Namespace, candidate.Name, candidate.Reason)
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		if cleanupDryRun {
+			return nil
+		}
+		if !cleanupYes && !confirm(fmt.Sprintf("Delete these %d resources?", len(candidates))) {
+			return nil
+		}
+		return deleteCandidates(candidates)
+	},
+}
+
+// kubeObject holds the fields of the fetched resources the scanners look at
+type kubeObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name            string    `json:"name"`
+		Namespace       string    `json:"namespace"`
+		OwnerReferences []nameRef `json:"ownerReferences"`
+	} `json:"metadata"`
+	// Type is set on secrets
+	Type string `json:"type"`
+	Spec struct {
+		podSpec
+		Replicas *int `json:"replicas"`
+		Template *struct {
+			Spec podSpec `json:"spec"`
+		} `json:"template"`
+		JobTemplate *struct {
+			Spec struct {
+				Template struct {
+					Spec podSpec `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"jobTemplate"`
+		TLS []struct {
+			SecretName string `json:"secretName"`
+		} `json:"tls"`
+	} `json:"spec"`
+	Status struct {
+		Phase      string `json:"phase"`
+		Reason     string `json:"reason"`
+		Replicas   int    `json:"replicas"`
+		Conditions []struct {
+			Type               string    `json:"type"`
+			Status             string    `json:"status"`
+			LastTransitionTime time.Time `json:"lastTransitionTime"`
+		} `json:"conditions"`
+	} `json:"status"`
+	// Secrets and ImagePullSecrets are set on service accounts
+	Secrets          []nameRef `json:"secrets"`
+	ImagePullSecrets []nameRef `json:"imagePullSecrets"`
+}
+
+// nameRef is any reference to another object by name
+type nameRef struct {
+	Name       string `json:"name"`
+	SecretName string `json:"secretName"`
+}
+
+// podSpec holds the parts of a pod spec that can reference config maps and secrets
+type podSpec struct {
+	Volumes []struct {
+		ConfigMap *nameRef `json:"configMap"`
+		Secret    *nameRef `json:"secret"`
+		Projected *struct {
+			Sources []struct {
+				ConfigMap *nameRef `json:"configMap"`
+				Secret    *nameRef `json:"secret"`
+			} `json:"sources"`
+		} `json:"projected"`
+	} `json:"volumes"`
+	Containers          []containerRefs `json:"containers"`
+	InitContainers      []containerRefs `json:"initContainers"`
+	EphemeralContainers []containerRefs `json:"ephemeralContainers"`
+	ImagePullSecrets    []nameRef       `json:"imagePullSecrets"`
+}
+
+// containerRefs holds the parts of a container that can reference config maps and secrets
+type containerRefs struct {
+	Env []struct {
+		ValueFrom *struct {
+			ConfigMapKeyRef *nameRef `json:"configMapKeyRef"`
+			SecretKeyRef    *nameRef `json:"secretKeyRef"`
+		} `json:"valueFrom"`
+	} `json:"env"`
+	EnvFrom []struct {
+		ConfigMapRef *nameRef `json:"configMapRef"`
+		SecretRef    *nameRef `json:"secretRef"`
+	} `json:"envFrom"`
+}
+
+// cleanupCandidate is a resource a scanner found safe to delete, and why
+type cleanupCandidate struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Reason    string
+}
+
+// cleanupScanner returns why the object should be deleted, or "" to keep it, given the config maps and secrets
+// the fetched objects reference
+type cleanupScanner func(object kubeObject, used references, now time.Time) string
+
+// cleanupScanners maps each kind that can be cleaned up to its scanner
+var cleanupScanners = map[string]cleanupScanner{
+	"Pod": func(pod kubeObject, used references, now time.Time) string {
+		switch {
+		case pod.Status.Phase == "Succeeded":
+			return "completed"
+		case pod.Status.Phase == "Failed" && pod.Status.Reason == "Evicted":
+			return "evicted"
+		}
+		return ""
+	},
+	"Job": func(job kubeObject, used references, now time.Time) string {
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == "Failed" && condition.Status == "True" {
+				age := now.Sub(condition.LastTransitionTime)
+				if age > time.Duration(cleanupJobDays)*24*time.Hour {
+					return fmt.Sprintf("failed %d days ago", int(age.Hours()/24))
+				}
+			}
+		}
+		return ""
+	},
+	"ConfigMap": func(configMap kubeObject, used references, now time.Time) string {
+		if slices.Contains(cleanupIgnoredConfigMaps, configMap.Metadata.Name) || len(configMap.Metadata.OwnerReferences) > 0 {
+			return ""
+		}
+		if !used.has("ConfigMap", configMap.Metadata.Namespace, configMap.Metadata.Name) {
+			return "not referenced"
+		}
+		return ""
+	},
+	"Secret": func(secret kubeObject, used references, now time.Time) string {
+		if slices.Contains(cleanupIgnoredSecretTypes, secret.Type) || len(secret.Metadata.OwnerReferences) > 0 {
+			return ""
+		}
+		if !used.has("Secret", secret.Metadata.Namespace, secret.Metadata.Name) {
+			return "not referenced"
+		}
+		return ""
+	},
+	"ReplicaSet": func(replicaSet kubeObject, used references, now time.Time) string {
+		if replicaSet.Spec.Replicas != nil && *replicaSet.Spec.Replicas == 0 && replicaSet.Status.Replicas == 0 {
+			return "scaled to zero"
+		}
+		return ""
+	},
+}
+
+// cleanupKindOrder is the order candidates are listed and deleted in
+var cleanupKindOrder = []string{"Pod", "Job", "ReplicaSet", "ConfigMap", "Secret"}
+
+// getCleanupObjects fetches every object of cleanupKinds in the scanned namespaces
+func getCleanupObjects() ([]kubeObject, error) {
+	args := []string{"get", strings.Join(cleanupKinds, ","), "-o=json"}
+	args = append(args, cleanupScope()...)
+	output, err := kubectlOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []kubeObject `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("parsing kubectl get output: %w", err)
+	}
+	return list.Items, nil
+}
+
+// scanForCleanup runs each object through its kind's scanner and returns the candidates ordered by kind, then
+// namespace and name
+func scanForCleanup(objects []kubeObject, now time.Time) []cleanupCandidate {
+	used := collectReferences(objects)
+	var candidates []cleanupCandidate
+	for _, object := range objects {
+		scan, ok := cleanupScanners[object.Kind]
+		if !ok || (isSystemNamespace(object.Metadata.Namespace) && !cleanupIncludeSystem) {
+			continue
+		}
+		if slices.Contains(cleanupConfigKinds, object.Kind) && !cleanupUnreferencedConfig {
+			continue
+		}
+		if reason := scan(object, used, now); reason != "" {
+			candidates = append(candidates, cleanupCandidate{
+				Kind:      object.Kind,
+				Namespace: object.Metadata.Namespace,
+				Name:      object.Metadata.Name,
+				Reason:    reason,
+			})
+		}
+	}
+	slices.SortFunc(candidates, func(a, b cleanupCandidate) int {
+		if a.Kind != b.Kind {
+			return slices.Index(cleanupKindOrder, a.Kind) - slices.Index(cleanupKindOrder, b.Kind)
+		}
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	return candidates
+}
+
+// isSystemNamespace reports whether the namespace belongs to the cluster's own components
+func isSystemNamespace(namespace string) bool {
+	return strings.HasPrefix(namespace, cleanupSystemNamespacePrefix)
+}
+
+// references holds the config maps and secrets referenced by name, keyed by kind, namespace and name
+type references map[string]struct{}
+
+func (r references) add(kind, namespace, name string) {
+	if name != "" {
+		r[kind+"/"+namespace+"/"+name] = struct{}{}
+	}
+}
+
+func (r references) has(kind, namespace, name string) bool {
+	_, exists := r[kind+"/"+namespace+"/"+name]
+	return exists
+}
+
+// collectReferences returns the config maps and secrets referenced by the pods and pod templates, the service
+// accounts and the ingresses among objects
+func collectReferences(objects []kubeObject) references {
+	used := make(references)
+	for _, object := range objects {
+		namespace := object.Metadata.Namespace
+		addPodSpecReferences(used, namespace, object.Spec.podSpec)
+		if object.Spec.Template != nil {
+			addPodSpecReferences(used, namespace, object.Spec.Template.Spec)
+		}
+		if object.Spec.JobTemplate != nil {
+			addPodSpecReferences(used, namespace, object.Spec.JobTemplate.Spec.Template.Spec)
+		}
+		for _, tls := range object.Spec.TLS {
+			used.add("Secret", namespace, tls.SecretName)
+		}
+		for _, secret := range append(object.Secrets, object.ImagePullSecrets...) {
+			used.add("Secret", namespace, secret.Name)
+		}
+	}
+	return used
+}
+
+// addPodSpecReferences adds the config maps and secrets the pod spec mounts, reads into its environment or pulls
+// images with
+func addPodSpecReferences(used references, namespace string, spec podSpec) {
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			used.add("ConfigMap", namespace, volume.ConfigMap.Name)
+		}
+		if volume.Secret != nil {
+			used.add("Secret", namespace, volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					used.add("ConfigMap", namespace, source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					used.add("Secret", namespace, source.Secret.Name)
+				}
+			}
+		}
+	}
+	for _, secret := range spec.ImagePullSecrets {
+		used.add("Secret", namespace, secret.Name)
+	}
+
+	containers := append(append(slices.Clone(spec.Containers), spec.InitContainers...), spec.EphemeralContainers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				used.add("ConfigMap", namespace, env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				used.add("Secret", namespace, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				used.add("ConfigMap", namespace, envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				used.add("Secret", namespace, envFrom.SecretRef.Name)
+			}
+		}
+	}
+}
+
+// deleteCandidates deletes the candidates with one kubectl delete per namespace
+func deleteCandidates(candidates []cleanupCandidate) error {
+	var namespaces []string
+	byNamespace := make(map[string][]string)
+	for _, candidate := range candidates {
+		if _, seen := byNamespace[candidate.Namespace]; !seen {
+			namespaces = append(namespaces, candidate.Namespace)
+		}
+		byNamespace[candidate.Namespace] = append(byNamespace[candidate.Namespace], strings.ToLower(candidate.Kind)+"/"+candidate.Name)
+	}
+	for _, namespace := range namespaces {
+		args := append([]string{"delete", "--namespace=" + namespace}, byNamespace[namespace]...)
+		command := exec.Command("kubectl", args...)
+		command.Stdout, command.Stderr = os.Stdout, os.Stderr
+		if err := command.Run(); err != nil {
+			return fmt.Errorf("deleting in %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// cleanupScope returns the kubectl flags selecting the scanned namespaces
+func cleanupScope() []string {
+	if cleanupAllNamespaces {
+		return []string{"--all-namespaces"}
+	}
+	return namespaceFlag(cleanupNamespace)
+}
+
+// confirm asks the question on stdout and reports whether the answer read from stdin is yes
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// secretObject returns a secret in the namespace that nothing references
+func secretObject(namespace, name, secretType string) kubeObject {
+	var secret kubeObject
+	secret.Kind = "Secret"
+	secret.Metadata.Namespace = namespace
+	secret.Metadata.Name = name
+	secret.Type = secretType
+	return secret
+}
+
+func TestScanForCleanup(t *testing.T) {
+	objects := []kubeObject{
+		secretObject("default", "unused", "Opaque"),
+		secretObject("default", "sa-token", "kubernetes.io/service-account-token"),
+		secretObject("kube-system", "bootstrap-token-abcdef", "bootstrap.kubernetes.io/token"),
+		secretObject("default", "bootstrap-token-ghijkl", "bootstrap.kubernetes.io/token"),
+		secretObject("default", "sh.helm.release.v1.web.v1", "helm.sh/release.v1"),
+		secretObject("kube-system", "leftover", "Opaque"),
+		secretObject("kube-public", "leftover", "Opaque"),
+	}
+	tests := []struct {
+		name          string
+		includeSystem bool
+		want          []string
+	}{
+		{"skips system namespaces", false, []string{"default/unused"}},
+		{"includes system namespaces", true, []string{"default/unused", "kube-public/leftover", "kube-system/leftover"}},
+	}
+	withFlag(t, &cleanupUnreferencedConfig, true)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previous := cleanupIncludeSystem
+			cleanupIncludeSystem = test.includeSystem
+			t.Cleanup(func() { cleanupIncludeSystem = previous })
+
+			var got []string
+			for _, candidate := range scanForCleanup(objects, time.Now()) {
+				got = append(got, candidate.Namespace+"/"+candidate.Name)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("cleaning up %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsSystemNamespace(t *testing.T) {
+	tests := map[string]bool{
+		"kube-system":     true,
+		"kube-public":     true,
+		"kube-node-lease": true,
+		"default":         false,
+		"kubernetes":      false,
+		"":                false,
+	}
+	for namespace, want := range tests {
+		if got := isSystemNamespace(namespace); got != want {
+			t.Errorf("isSystemNamespace(%q) = %v, want %v", namespace, got, want)
+		}
+	}
+}
+
+// cleanupList is the kubectl get output of a namespace, with a candidate for every scanner next to objects each
+// scanner has to keep
+const cleanupList = `{"items": [
+{"kind": "Pod", "metadata": {"namespace": "web", "name": "migrate-x1"}, "status": {"phase": "Succeeded"}},
+{"kind": "Pod", "metadata": {"namespace": "web", "name": "api-evicted"}, "status": {"phase": "Failed", "reason": "Evicted"}},
+{"kind": "Pod", "metadata": {"namespace": "web", "name": "api-crashed"}, "status": {"phase": "Failed", "reason": "Error"}},
+{"kind": "Pod", "metadata": {"namespace": "web", "name": "api-0"}, "status": {"phase": "Running"}, "spec": {
+	"volumes": [{"configMap": {"name": "api-config"}}, {"projected": {"sources": [{"secret": {"name": "api-certs"}}]}}],
+	"initContainers": [{"envFrom": [{"secretRef": {"name": "api-db"}}]}],
+	"containers": [{"env": [{"name": "PLAIN", "value": "x"}, {"valueFrom": {"configMapKeyRef": {"name": "api-flags"}}}]}]}},
+{"kind": "Job", "metadata": {"namespace": "web", "name": "backfill"}, "status": {"conditions": [
+	{"type": "Failed", "status": "True", "lastTransitionTime": "2024-01-01T00:00:00Z"}]}},
+{"kind": "Job", "metadata": {"namespace": "web", "name": "backfill-retry"}, "status": {"conditions": [
+	{"type": "Failed", "status": "True", "lastTransitionTime": "2024-01-28T00:00:00Z"}]}},
+{"kind": "CronJob", "metadata": {"namespace": "web", "name": "report"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {
+	"containers": [{"envFrom": [{"configMapRef": {"name": "report-config"}}]}]}}}}}},
+{"kind": "Deployment", "metadata": {"namespace": "web", "name": "worker"}, "spec": {"template": {"spec": {
+	"volumes": [{"secret": {"secretName": "worker-key"}}], "imagePullSecrets": [{"name": "registry"}]}}}},
+{"kind": "ReplicaSet", "metadata": {"namespace": "web", "name": "worker-old"}, "spec": {"replicas": 0}, "status": {"replicas": 0}},
+{"kind": "ReplicaSet", "metadata": {"namespace": "web", "name": "worker-scaling-down"}, "spec": {"replicas": 0}, "status": {"replicas": 1}},
+{"kind": "ReplicaSet", "metadata": {"namespace": "web", "name": "worker-new"}, "spec": {"replicas": 2}, "status": {"replicas": 2}},
+{"kind": "ServiceAccount", "metadata": {"namespace": "web", "name": "deployer"}, "secrets": [{"name": "deployer-token"}]},
+{"kind": "Ingress", "metadata": {"namespace": "web", "name": "api"}, "spec": {"tls": [{"secretName": "api-tls"}]}},
+{"kind": "ConfigMap", "metadata": {"namespace": "web", "name": "api-config"}},
+{"kind": "ConfigMap", "metadata": {"namespace": "web", "name": "api-flags"}},
+{"kind": "ConfigMap", "metadata": {"namespace": "web", "name": "report-config"}},
+{"kind": "ConfigMap", "metadata": {"namespace": "web", "name": "kube-root-ca.crt"}},
+{"kind": "ConfigMap", "metadata": {"namespace": "web", "name": "owned", "ownerReferences": [{"name": "worker"}]}},
+{"kind": "ConfigMap", "metadata": {"namespace": "web", "name": "old-flags"}},
+{"kind": "Secret", "metadata": {"namespace": "web", "name": "api-certs"}, "type": "Opaque"},
+{"kind": "Secret", "metadata": {"namespace": "web", "name": "api-db"}, "type": "Opaque"},
+{"kind": "Secret", "metadata": {"namespace": "web", "name": "api-tls"}, "type": "kubernetes.io/tls"},
+{"kind": "Secret", "metadata": {"namespace": "web", "name": "worker-key"}, "type": "Opaque"},
+{"kind": "Secret", "metadata": {"namespace": "web", "name": "registry"}, "type": "kubernetes.io/dockerconfigjson"},
+{"kind": "Secret", "metadata": {"namespace": "web", "name": "deployer-token"}, "type": "Opaque"},
+{"kind": "Secret", "metadata": {"namespace": "other", "name": "api-db"}, "type": "Opaque"}
+]}`
+
+func TestCleanupScanners(t *testing.T) {
+	dir := fakeKubectl(t, "printf '%s\\n' \"$@\" > \"$KT_TEST_DIR/args\"\ncat <<'EOF'\n"+cleanupList+"\nEOF\n")
+	withFlag(t, &cleanupAllNamespaces, true)
+	objects, err := getCleanupObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args, _ := os.ReadFile(filepath.Join(dir, "args")); !strings.HasSuffix(string(args), "-o=json\n--all-namespaces\n") {
+		t.Errorf("kubectl was run with %q", args)
+	}
+
+	// Config maps and secrets are left alone without --unreferenced-config
+	now := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	want := []cleanupCandidate{
+		{"Pod", "web", "api-evicted", "evicted"},
+		{"Pod", "web", "migrate-x1", "completed"},
+		{"Job", "web", "backfill", "failed 31 days ago"},
+		{"ReplicaSet", "web", "worker-old", "scaled to zero"},
+	}
+	if got := scanForCleanup(objects, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("got candidates\n%v\nwant\n%v", got, want)
+	}
+
+	// A reference only counts in its own namespace, so the api-db of other is unused
+	withFlag(t, &cleanupUnreferencedConfig, true)
+	want = append(want, cleanupCandidate{"ConfigMap", "web", "old-flags", "not referenced"}, cleanupCandidate{"Secret", "other", "api-db", "not referenced"})
+	if got := scanForCleanup(objects, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("with --unreferenced-config got candidates\n%v\nwant\n%v", got, want)
+	}
+
+	// A job counts as old from --failed-job-days
+	withFlag(t, &cleanupJobDays, 2)
+	var jobs []string
+	for _, candidate := range scanForCleanup(objects, now) {
+		if candidate.Kind == "Job" {
+			jobs = append(jobs, candidate.Name+": "+candidate.Reason)
+		}
+	}
+	if want := []string{"backfill: failed 31 days ago", "backfill-retry: failed 4 days ago"}; !slices.Equal(jobs, want) {
+		t.Errorf("with --failed-job-days 2 got jobs %v, want %v", jobs, want)
+	}
+}
+
+func TestCleanupRejectsSystemNamespace(t *testing.T) {
+	// The namespace is refused before anything is fetched
+	dir := fakeKubectl(t, "touch \"$KT_TEST_DIR/ran\"\n")
+	withFlag(t, &cleanupNamespace, "kube-system")
+	err := cleanupCmd.RunE(cleanupCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "add --include-system") {
+		t.Errorf("got %v, want kube-system refused", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err == nil {
+		t.Error("kubectl was run for a system namespace")
+	}
+}
+
+func TestDeleteCandidates(t *testing.T) {
+	dir := fakeKubectl(t, "echo \"$*\" >> \"$KT_TEST_DIR/deletes\"\n")
+	candidates := []cleanupCandidate{
+		{"Pod", "web", "migrate-x1", "completed"},
+		{"Pod", "other", "job-x2", "completed"},
+		{"ConfigMap", "web", "old-flags", "not referenced"},
+	}
+	if err := deleteCandidates(candidates); err != nil {
+		t.Fatal(err)
+	}
+	// One delete per namespace, in the order the namespaces first appear
+	got, _ := os.ReadFile(filepath.Join(dir, "deletes"))
+	if want := "delete --namespace=web pod/migrate-x1 configmap/old-flags\ndelete --namespace=other pod/job-x2\n"; string(got) != want {
+		t.Errorf("got deletes\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// APIResource is a single row of kubectl api-resources
+type APIResource struct {
+	Name       string
+	ShortNames []string
+	APIVersion string
+	Namespaced bool
+	Kind       string
+}
+
+// discoverAPIResources lists the resources served by the current cluster using kubectl, passing any
+// extra arguments through to filter them
+func discoverAPIResources(args ...string) ([]APIResource, error) {
+	output, err := exec.Command("kubectl", append([]string{"api-resources", "--no-headers"}, args...)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("kubectl api-resources failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("kubectl api-resources failed: %w", err)
+	}
+	return parseAPIResources(string(output))
+}
+
+// parseAPIResources parses the output of kubectl api-resources --no-headers. The SHORTNAMES column
+// is empty for many resources, so rows have either four or five columns
+func parseAPIResources(output string) ([]APIResource, error) {
+	var resources []APIResource
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 && len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected api-resources line %q", line)
+		}
+
+		n := len(fields)
+		resource := APIResource{
+			Name:       fields[0],
+			APIVersion: fields[n-3],
+			Namespaced: fields[n-2] == "true",
+			Kind:       fields[n-1],
+		}
+		if n == 5 {
+			resource.ShortNames = strings.Split(fields[1], ",")
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// matches reports whether name, with any group qualification removed, refers to this resource
+// by its plural name, its kind or one of its short names
+func (r APIResource) matches(name string) bool {
+	name, _, _ = strings.Cut(strings.ToLower(name), ".")
+	if name == r.Name || name == strings.ToLower(r.Kind) {
+		return true
+	}
+	for _, short := range r.ShortNames {
+		if name == short {
+			return true
+		}
+	}
+	return false
+}
+
+// intersectResources keeps only the resources that are served by the cluster. Parts whose expansion
+// doesn't name a resource type, such as the auth reviews, are always kept
+func intersectResources(resources []Part, served []APIResource) []Part {
+	var kept []Part
+	for _, resource := range resources {
+		if isServed(resource, served) {
+			kept = append(kept, resource)
+		}
+	}
+	return kept
+}
+
+// unknownResources returns the resources the cluster doesn't serve, the complement of intersectResources
+func unknownResources(resources []Part, served []APIResource) []Part {
+	var unknown []Part
+	for _, resource := range resources {
+		if !isServed(resource, served) {
+			unknown = append(unknown, resource)
+		}
+	}
+	return unknown
+}
+
+// isServed reports whether the resource's expansion names one of the served resources, treating
+// expansions that don't name a resource type as served
+func isServed(resource Part, served []APIResource) bool {
+	if strings.HasPrefix(resource.Full, "-") {
+		return true
+	}
+	for _, apiResource := range served {
+		if apiResource.matches(resource.Full) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterResources returns a resource part for each served resource that none of the existing resources
+// already names. Each is aliased by its first short name that isn't taken, or else by the shortest free
+// prefix of its plural name, and cluster-scoped resources don't combine with a namespace
+func clusterResources(existing []Part, served []APIResource, taken map[string]struct{}) []Part {
+	var added []Part
+	for _, apiResource := range served {
+		known := slices.ContainsFunc(existing, func(resource Part) bool { return apiResource.matches(resource.Full) }) ||
+			slices.ContainsFunc(added, func(resource Part) bool { return apiResource.matches(resource.Full) })
+		if known {
+			continue
+		}
+
+		alias := ""
+		for _, short := range apiResource.ShortNames {
+			if _, exists := taken[aliases.NormalizeAlias(short)]; !exists && aliases.NormalizeAlias(short) != "" {
+				alias = aliases.NormalizeAlias(short)
+				break
+			}
+		}
+		name := aliases.NormalizeAlias(apiResource.Name)
+		for n := min(3, len(name)); alias == "" && n <= len(name); n++ {
+			if _, exists := taken[name[:n]]; !exists {
+				alias = name[:n]
+			}
+		}
+		if alias == "" {
+			continue
+		}
+		taken[alias] = struct{}{}
+
+		var incompatible []string
+		if !apiResource.Namespaced {
+			incompatible = aliases.ClusterScoped()
+		}
+		added = append(added, Part{
+			Alias:            alias,
+			Full:             apiResource.Name,
+			AllowWhenOneOf:   []string{"g", "d", "rm"},
+			IncompatibleWith: incompatible,
+			Category:         "cluster",
+		})
+	}
+	return added
+}
+
+// checkClusterResources warns on w, or fails with --max-resources-fail, when the cluster added more resources than
+// --max-resources-per-alias, as each can give dozens of aliases
+func checkClusterResources(added []Part, w io.Writer) error {
+	if maxClusterResources == 0 || len(added) <= maxClusterResources {
+		return nil
+	}
+	message := fmt.Sprintf("--from-cluster added %d resources, more than --max-resources-per-alias=%d, "+
+		"narrow them with --include, --exclude or --resources", len(added), maxClusterResources)
+	if maxClusterResourcesFail {
+		return errors.New(message)
+	}
+	fmt.Fprintf(w, "warning: %s\n", message)
+	return nil
+}
+
+// explainResource returns the first line of the resource's description from kubectl explain
+func explainResource(resource string) (string, error) {
+	output, err := exec.Command("kubectl", "explain", resource).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("kubectl explain %s failed: %s", resource, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("kubectl explain %s failed: %w", resource, err)
+	}
+	return parseExplainDescription(string(output)), nil
+}
+
+// parseExplainDescription returns the first non-empty line following the DESCRIPTION: heading
+func parseExplainDescription(output string) string {
+	inDescription := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "DESCRIPTION:" {
+			inDescription = true
+			continue
+		}
+		if inDescription && line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// describeResources runs kubectl explain once for each resource and maps its expansion to the first line of
+// its description. Resources that can't be explained are left out, and discovery stops at the first
+// failure to run kubectl at all
+func describeResources(resources []Part) (map[string]string, error) {
+	descriptions := make(map[string]string)
+	for _, resource := range resources {
+		if strings.HasPrefix(resource.Full, "-") {
+			continue
+		}
+		if _, done := descriptions[resource.Full]; done {
+			continue
+		}
+		description, err := explainResource(resource.Full)
+		if err != nil {
+			var execErr *exec.Error
+			if errors.As(err, &execErr) {
+				return descriptions, err
+			}
+			continue
+		}
+		descriptions[resource.Full] = description
+	}
+	return descriptions, nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// servedResources is kubectl api-resources --no-headers for a cluster without ingresses
+const servedResources = `pods                              po           v1                     true         Pod
+services                          svc          v1                     true         Service
+deployments                       deploy       apps/v1                true         Deployment
+`
+
+func TestClusterResources(t *testing.T) {
+	existing := []Part{{Alias: "po", Full: "pods"}}
+	served := []APIResource{
+		{Name: "pods", ShortNames: []string{"po"}, Namespaced: true},
+		{Name: "widgets", ShortNames: []string{"wd"}, APIVersion: "example.com/v1", Namespaced: true},
+		{Name: "gadgets", ShortNames: []string{"po"}, APIVersion: "example.com/v1", Namespaced: false},
+	}
+	taken := map[string]struct{}{"po": {}}
+	want := []Part{
+		{Alias: "wd", Full: "widgets", AllowWhenOneOf: []string{"g", "d", "rm"}, Category: "cluster"},
+		// A taken short name falls back to a prefix of the name, and cluster-scoped resources get the same rule
+		// as the built-in ones
+		{Alias: "gad", Full: "gadgets", AllowWhenOneOf: []string{"g", "d", "rm"}, IncompatibleWith: aliases.ClusterScoped(), Category: "cluster"},
+	}
+	if got := clusterResources(existing, served, taken); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestResourceAllowlistFromCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		kubectl string
+		want    []string
+		dropped []string
+	}{
+		{
+			name:    "served resources",
+			kubectl: "cat <<'EOF'\n" + servedResources + "EOF\n",
+			want:    []string{"alias kgpo='kubectl get pods'", "alias kgdep='kubectl get deployment'", "alias kgsvc='kubectl get service'"},
+			dropped: []string{"alias kging='kubectl get ingress'"},
+		},
+		{
+			name:    "discovery fails",
+			kubectl: "echo 'connection refused' >&2\nexit 1\n",
+			want:    []string{"alias kgpo='kubectl get pods'", "alias kging='kubectl get ingress'"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeKubectl(t, test.kubectl)
+			withFlag(t, &clusterAllowlist, true)
+			out := lines(generate(t))
+			for _, want := range test.want {
+				if !slices.Contains(out, want) {
+					t.Errorf("no %s", want)
+				}
+			}
+			for _, dropped := range test.dropped {
+				if slices.Contains(out, dropped) {
+					t.Errorf("unexpected %s", dropped)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAgainstCluster(t *testing.T) {
+	served, err := parseAPIResources(servedResources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configured := []Part{
+		{Alias: "po", Full: "pods"},
+		{Alias: "dep", Full: "deployment.apps"},
+		{Alias: "wd", Full: "widgets"},
+		// Flags such as the auth reviews' --raw aren't resource types, so they're never flagged
+		{Alias: "tr", Full: "--raw=/apis/authentication.k8s.io/v1/tokenreviews -f"},
+	}
+	if got, want := unknownResources(configured, served), configured[2:3]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// A failing discovery only skips the validation
+	fakeKubectl(t, "echo 'connection refused' >&2\nexit 1\n")
+	withFlag(t, &validateAgainstCluster, true)
+	if _, err := buildAliasGenerator(Config{Resources: configured}, "aliases.yaml"); err != nil {
+		t.Errorf("got %v when discovery fails", err)
+	}
+	if _, err := buildAliasGenerator(Config{}, ""); err == nil {
+		t.Error("got no error validating without a config file")
+	}
+}
+
+func TestMaxClusterResources(t *testing.T) {
+	// Three CRDs that aren't built in, one more than the limit
+	var crds strings.Builder
+	for _, name := range []string{"widgets", "gadgets", "gizmos"} {
+		fmt.Fprintf(&crds, "%s  example.com/v1  true  %s\n", name, strings.TrimSuffix(name, "s"))
+	}
+	fakeKubectl(t, "cat <<'EOF'\n"+crds.String()+"EOF\n")
+	withFlag(t, &fromCluster, true)
+
+	tests := []struct {
+		max     int
+		fail    bool
+		warning bool
+		err     bool
+	}{
+		{max: 3},
+		{max: 0},
+		{max: 2, warning: true},
+		{max: 2, fail: true, err: true},
+	}
+	for _, test := range tests {
+		withFlag(t, &maxClusterResources, test.max)
+		withFlag(t, &maxClusterResourcesFail, test.fail)
+
+		var warnings bytes.Buffer
+		served, err := parseAPIResources(crds.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkClusterResources(clusterResources(nil, served, map[string]struct{}{}), &warnings)
+		if got := strings.Contains(warnings.String(), "added 3 resources"); got != test.warning {
+			t.Errorf("max %d: got warning %q, want one %v", test.max, warnings.String(), test.warning)
+		}
+		if (err != nil) != test.err {
+			t.Errorf("max %d, fail %v: got error %v, want one %v", test.max, test.fail, err, test.err)
+		}
+
+		// The check runs on the discovery path
+		if _, err := buildAliasGenerator(Config{}, ""); (err != nil) != test.err {
+			t.Errorf("max %d, fail %v: buildAliasGenerator got error %v, want one %v", test.max, test.fail, err, test.err)
+		}
+	}
+}
+
+// explainPods is the start of kubectl explain pods
+const explainPods = `KIND:       Pod
+VERSION:    v1
+
+DESCRIPTION:
+    Pod is a collection of containers that can run on a host.
+
+FIELDS:
+`
+
+func TestDescribeFromKubectl(t *testing.T) {
+	// The fake kubectl logs each resource it's asked to explain, and can only explain pods
+	dir := fakeKubectl(t, "echo \"$2\" >> \"$KT_TEST_DIR/explained\"\n"+
+		"[ \"$2\" = pods ] || { echo 'no matches' >&2; exit 1; }\n"+
+		"cat <<'EOF'\n"+explainPods+"EOF\n")
+	withFlag(t, &describeFromKubectl, true)
+
+	out := lines(generate(t))
+	want := "# pods: Pod is a collection of containers that can run on a host."
+	if i := slices.Index(out, want); i == -1 || !strings.Contains(out[i+1], "pods") {
+		t.Errorf("no %q above the first pods alias", want)
+	}
+	for _, line := range out {
+		if strings.HasPrefix(line, "# ") && line != want && !strings.HasPrefix(line, "# Generated") {
+			t.Errorf("unexpected comment %q", line)
+		}
+	}
+
+	explained, err := os.ReadFile(filepath.Join(dir, "explained"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]struct{})
+	for _, resource := range lines(string(explained)) {
+		if _, done := seen[resource]; done {
+			t.Errorf("explained %s more than once", resource)
+		}
+		seen[resource] = struct{}{}
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+func init() {
+	aliasesCmd.AddCommand(completionsCmd)
+}
+
+var completionsCmd = &cobra.Command{
+	Use:   "completions",
+	Short: "Generates completion shims so the aliases complete like the commands they run",
+	Long: "Generates, for --shell, a completion registration for every alias the same flags generate, which" +
+		"\nexpands the alias and hands the command line to the completion of the command it runs, so kgpo <Tab>" +
+		"\ncompletes pod names like kubectl get pods <Tab>. Source it after the commands' own completion, e.g." +
+		"\nsource <(kubectl completion bash). zsh only needs it with the complete_aliases option set, where each alias" +
+		"\ncompletes as its whole expansion, not just the bare command.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		writeShims, ok := completionShims[aliasShell]
+		if !ok {
+			return fmt.Errorf("completions aren't supported for %s", aliasShell)
+		}
+		ag, err := newAliasGenerator()
+		if err != nil {
+			return err
+		}
+		names, expansions := completedAliases(&ag)
+		writeShims(os.Stdout, names, expansions)
+		return nil
+	},
+}
+
+// completedAliases returns the names of the generated aliases, with the command each completes as
+func completedAliases(ag *AliasGenerator) ([]string, map[string]string) {
+	// The names have to be the ones the aliases are written with, after conflicts have been resolved
+	generated := ag.Generate()
+	if ag.Conflicts != nil {
+		generated = ag.Conflicts.resolve(generated)
+	}
+	expansions := make(map[string]string)
+	var names []string
+	for _, alias := range aliases.Dedup(generated) {
+		names = append(names, alias.Name)
+		expansions[alias.Name] = ag.completedCommand(alias)
+	}
+	return names, expansions
+}
+
+// completionShims write the completion registrations of the named aliases in each shell's syntax
+var completionShims = map[string]func(out io.Writer, names []string, expansions map[string]string){
+	// bash doesn't expand aliases when completing, so the words are rewritten to the expansion before calling
+	// the cobra completion function of the command, which kubectl, helm and istioctl all define
+	"bash": func(out io.Writer, names []string, expansions map[string]string) {
+		fmt.Fprintln(out, comment("Generated alias completions for bash"))
+		fmt.Fprintln(out, "declare -A __kt_alias_expansions=(")
+		for _, name := range names {
+			fmt.Fprintf(out, "  [%s]=%s\n", name, singleQuote("bash", expansions[name]))
+		}
+		fmt.Fprintln(out, ")")
+		fmt.Fprint(out, `__kt_complete_alias() {
+  local expansion=${__kt_alias_expansions[$1]}
+  local -a words
+  read -ra words <<< "$expansion"
+  declare -F "__start_${words[0]}" > /dev/null || return
+  COMP_LINE=$expansion${COMP_LINE:${#1}}
+  COMP_POINT=$((COMP_POINT + ${#expansion} - ${#1}))
+  COMP_WORDS=("${words[@]}" "${COMP_WORDS[@]:1}")
+  COMP_CWORD=$((COMP_CWORD + ${#words[@]} - 1))
+  "__start_${words[0]}" "${words[0]}" "$2" "$3"
+}
+`)
+		for _, name := range names {
+			fmt.Fprintf(out, "complete -o default -F __kt_complete_alias %s\n", name)
+		}
+	},
+	// zsh expands aliases before completing unless complete_aliases is set, in which case _normal completes the
+	// rewritten words as the command they start with
+	"zsh": func(out io.Writer, names []string, expansions map[string]string) {
+		fmt.Fprintln(out, comment("Generated alias completions for zsh with complete_aliases set"))
+		fmt.Fprintln(out, "typeset -gA __kt_alias_expansions=(")
+		for _, name := range names {
+			fmt.Fprintf(out, "  %s %s\n", name, singleQuote("zsh", expansions[name]))
+		}
+		fmt.Fprintln(out, ")")
+		fmt.Fprint(out, `__kt_complete_alias() {
+  local -a expansion=(${(z)__kt_alias_expansions[$words[1]]})
+  words=($expansion $words[2,-1])
+  (( CURRENT += $#expansion - 1 ))
+  _normal
+}
+`)
+		fmt.Fprintln(out, "if (( $+functions[compdef] )); then")
+		for _, name := range names {
+			fmt.Fprintf(out, "  compdef __kt_complete_alias %s\n", name)
+		}
+		fmt.Fprintln(out, "fi")
+	},
+	// fish completes a command as the command line it wraps, which the aliases defined as functions don't declare
+	"fish": func(out io.Writer, names []string, expansions map[string]string) {
+		fmt.Fprintln(out, comment("Generated alias completions for fish"))
+		for _, name := range names {
+			fmt.Fprintf(out, "complete -c %s -w %s\n", name, singleQuote("fish", expansions[name]))
+		}
+	},
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// quotedExpansions holds an expansion with single quotes, which every shell's quoting has to keep intact
+var quotedExpansions = map[string]string{
+	"kgpo": "kubectl get pods",
+	"kgpq": `kubectl get pods -l 'app in (a,b)' -o=jsonpath='{.items[*]}'`,
+}
+
+func TestCompletionShims(t *testing.T) {
+	tests := map[string][]string{
+		"bash": {
+			"# Generated alias completions for bash",
+			"declare -A __kt_alias_expansions=(",
+			"  [kgpo]='kubectl get pods'",
+			`  [kgpq]='kubectl get pods -l '\''app in (a,b)'\'' -o=jsonpath='\''{.items[*]}'\'''`,
+			")",
+			"__kt_complete_alias() {",
+			"complete -o default -F __kt_complete_alias kgpo",
+			"complete -o default -F __kt_complete_alias kgpq",
+		},
+		"zsh": {
+			"# Generated alias completions for zsh with complete_aliases set",
+			"typeset -gA __kt_alias_expansions=(",
+			"  kgpo 'kubectl get pods'",
+			`  kgpq 'kubectl get pods -l '\''app in (a,b)'\'' -o=jsonpath='\''{.items[*]}'\'''`,
+			")",
+			"__kt_complete_alias() {",
+			"if (( $+functions[compdef] )); then",
+			"  compdef __kt_complete_alias kgpo",
+			"  compdef __kt_complete_alias kgpq",
+			"fi",
+		},
+		"fish": {
+			"# Generated alias completions for fish",
+			"complete -c kgpo -w 'kubectl get pods'",
+			`complete -c kgpq -w 'kubectl get pods -l \'app in (a,b)\' -o=jsonpath=\'{.items[*]}\''`,
+		},
+	}
+	for shell, want := range tests {
+		var out bytes.Buffer
+		completionShims[shell](&out, []string{"kgpo", "kgpq"}, quotedExpansions)
+		got := lines(out.String())
+		for _, line := range want {
+			if !slices.Contains(got, line) {
+				t.Errorf("%s: output doesn't contain %s:\n%s", shell, line, out.String())
+			}
+		}
+	}
+}
+
+func TestBashCompletionShimsSource(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash isn't installed")
+	}
+	var out bytes.Buffer
+	completionShims["bash"](&out, []string{"kgpo", "kgpq"}, quotedExpansions)
+	path := filepath.Join(t.TempDir(), "completions.bash")
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sourcing the shims registers each alias and keeps its expansion as written
+	script := `source "$1" && complete -p kgpq && printf '%s\n' "${__kt_alias_expansions[kgpq]}"`
+	output, err := exec.Command("bash", "-c", script, "bash", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+	want := []string{"complete -o default -F __kt_complete_alias kgpq", quotedExpansions["kgpq"]}
+	if got := lines(string(output)); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompletedAliases(t *testing.T) {
+	withFlag(t, &deleteWrapper, "confirm")
+	ag, err := buildAliasGenerator(Config{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, expansions := completedAliases(&ag)
+	if !slices.Contains(names, "krmpo") || !slices.Contains(names, "kgpo") {
+		t.Fatalf("krmpo and kgpo aren't among the completed aliases")
+	}
+	// The wrapper is what runs, but the alias completes as the kubectl command it wraps
+	for name, want := range map[string]string{"krmpo": "kubectl delete pods", "kgpo": "kubectl get pods"} {
+		if got := expansions[name]; got != want {
+			t.Errorf("%s completes as %q, want %q", name, got, want)
+		}
+	}
+}
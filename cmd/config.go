@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Config holds the parts loaded from a --config file
+type Config = aliases.Config
+
+// Team bundles the namespace, resources and operations a team works with into a single alias set
+type Team = aliases.Team
+
+// defaultConfigPath returns where the config file is looked for when --config isn't given
+func defaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube-tools", "aliases.yaml"), nil
+}
+
+// configFile returns the config file to load: the --config path, or else the default path if that file exists,
+// or an empty string for the built-in parts alone
+func configFile() string {
+	if configPath != "" {
+		return configPath
+	}
+	path, err := defaultConfigPath()
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// marshalConfig renders the config as YAML with the two-space indentation used in the README examples
+func marshalConfig(config Config) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// configStdin is where the config is read from for --config -
+var configStdin io.Reader = os.Stdin
+
+// stdinConfig holds the config once it's been read from stdin
+var stdinConfig []byte
+
+// readConfig returns the contents of the config file at path, or of stdin for -. Stdin is only read once, as both
+// the presets and the aliases load the config
+func readConfig(path string) ([]byte, error) {
+	if path != "-" {
+		return os.ReadFile(path)
+	}
+	if stdinConfig == nil {
+		data, err := io.ReadAll(configStdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading config from stdin: %w", err)
+		}
+		stdinConfig = append([]byte{}, data...)
+	}
+	return stdinConfig, nil
+}
+
+// configFormatOf returns the format the config at path is parsed as: --config-format when it's given, else json
+// for a .json file and yaml for anything else, including stdin
+func configFormatOf(path string) string {
+	if configFormat != "" {
+		return configFormat
+	}
+	if filepath.Ext(path) == ".json" {
+		return "json"
+	}
+	return "yaml"
+}
+
+// loadConfig reads and parses a YAML or JSON config file, rejecting unknown fields and parts without an alias or
+// expansion
+func loadConfig(path string) (*Config, error) {
+	data, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := aliases.ParseConfig(data, configFormatOf(path))
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// warnUnknownResources reports on stderr each config resource the current cluster doesn't serve, which is
+// usually a typo or a CRD that isn't installed
+func warnUnknownResources(resources []Part) {
+	served, err := discoverAPIResources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: skipping cluster validation, %v\n", err)
+		return
+	}
+	for _, resource := range unknownResources(resources, served) {
+		fmt.Fprintf(os.Stderr, "warning: config resource %s (%s) isn't served by the cluster\n", resource.Alias, resource.Full)
+	}
+}
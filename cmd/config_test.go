@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Config
+		wantErr string
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			want:    &Config{},
+		},
+		{
+			name:    "parts and replace",
+			content: "replace: true\nresources:\n  - alias: rs\n    full: replicasets\n    allowWhenOneOf: [g]\ndeprecated: [rs]\n",
+			want: &Config{
+				Replace:    true,
+				Resources:  []Part{{Alias: "rs", Full: "replicasets", AllowWhenOneOf: []string{"g"}}},
+				Deprecated: []string{"rs"},
+			},
+		},
+		{
+			name:    "resource without an expansion",
+			content: "resources:\n  - alias: sa\n    allowWhenOneOf: [g]\n",
+			wantErr: "resources[0] needs both an alias and a full expansion",
+		},
+		{
+			name:    "unknown field",
+			content: "resource:\n  - alias: rs\n    full: replicasets\n",
+			wantErr: "field resource not found",
+		},
+		{
+			name:    "op without an expansion",
+			content: "ops:\n  - alias: ed\n",
+			wantErr: "ops[0] needs both an alias and a full expansion",
+		},
+		{
+			name:    "part without an alias",
+			content: "resources:\n  - full: replicasets\n",
+			wantErr: "resources[0] needs both an alias and a full expansion",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aliases.yaml")
+			if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadConfig(path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildAliasGeneratorWithConfig(t *testing.T) {
+	config := Config{
+		Resources: []Part{{Alias: "rs", Full: "replicasets", AllowWhenOneOf: []string{"g"}}},
+		Ops:       []Part{{Alias: "ed", Full: "edit"}},
+	}
+	ag, err := buildAliasGenerator(config, "aliases.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := make(map[string]string)
+	for _, alias := range ag.Generate() {
+		commands[alias.Name] = alias.Command
+	}
+	for name, want := range map[string]string{"kgrs": "kubectl get replicasets", "kgpo": "kubectl get pods", "ked": "kubectl edit"} {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+
+	replacing := Config{Replace: true, Commands: []Part{{Alias: "kc", Full: "kubecolor"}}}
+	if ag, err = buildAliasGenerator(replacing, "aliases.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ag.Commands; !reflect.DeepEqual(got, replacing.Commands) {
+		t.Errorf("commands are %v, want only the config's", got)
+	}
+	if got := len(ag.Resources); got != len(aliases.Resources()) {
+		t.Errorf("got %d resources, want the %d built-in ones the config leaves alone", got, len(aliases.Resources()))
+	}
+
+	config.Resources[0].AllowWhenOneOf = []string{"missing"}
+	if _, err := buildAliasGenerator(config, "aliases.yaml"); err == nil || !strings.Contains(err.Error(), `unknown alias "missing"`) {
+		t.Errorf("got error %v for a reference to an unknown alias", err)
+	}
+}
+
+func TestLoadConfigFromStdin(t *testing.T) {
+	want := &Config{Ops: []Part{{Alias: "ed", Full: "edit", AllowWhenOneOf: []string{"po"}}}}
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		wantErr string
+	}{
+		{"yaml by default", "", "ops:\n  - alias: ed\n    full: edit\n    allowWhenOneOf: [po]\n", ""},
+		{"json", "json", `{"ops": [{"alias": "ed", "full": "edit", "allowWhenOneOf": ["po"]}]}`, ""},
+		{"yaml given as json", "json", "ops:\n  - alias: ed\n    full: edit\n", "as JSON"},
+		{"unknown json field", "json", `{"op": []}`, "field op not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withFlag(t, &configFormat, test.format)
+			withFlag(t, &configStdin, io.Reader(strings.NewReader(test.content)))
+			withFlag(t, &stdinConfig, nil)
+			got, err := loadConfig("-")
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+			// The presets load the config before the aliases do, so stdin has to be kept rather than read again
+			if again, err := loadConfig("-"); err != nil || !reflect.DeepEqual(again, want) {
+				t.Errorf("loading stdin again got %+v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestConfigFormatOf(t *testing.T) {
+	tests := []struct {
+		path   string
+		flag   string
+		format string
+	}{
+		{"aliases.yaml", "", "yaml"},
+		{"aliases.yml", "", "yaml"},
+		{"aliases.json", "", "json"},
+		{"-", "", "yaml"},
+		{"aliases.json", "yaml", "yaml"},
+		{"aliases.conf", "json", "json"},
+	}
+	for _, test := range tests {
+		withFlag(t, &configFormat, test.flag)
+		if got := configFormatOf(test.path); got != test.format {
+			t.Errorf("%s with --config-format %q is parsed as %s, want %s", test.path, test.flag, got, test.format)
+		}
+	}
+}
+
+func TestTeam(t *testing.T) {
+	config := Config{Teams: map[string]Team{
+		"payments": {Namespace: "payments", Resources: []string{"dep", "po", "svc"}, Ops: []string{"g", "d", "rm", "lo", "ex"}},
+		"web":      {Namespace: "web", Resources: []string{"po"}, Ops: []string{"g", "lo"}},
+		"bad-ns":   {Namespace: "Web_1"},
+		"bad-res":  {Namespace: "web", Resources: []string{"nope"}},
+		"bad-op":   {Namespace: "web", Ops: []string{"nope"}},
+	}}
+	bundle := func(t *testing.T, team string) ([]string, error) {
+		t.Helper()
+		withFlag(t, &aliasTeam, team)
+		ag, err := buildAliasGenerator(config, "teams.yaml")
+		if err != nil {
+			return nil, err
+		}
+		out, err := render(&ag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lines(string(out))[1:], nil
+	}
+
+	// A single resource is compacted out of the alias names
+	web, err := bundle(t, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"alias klo='kubectl --namespace=web logs -f'",
+		"alias kgoyamll='kubectl --namespace=web get pods -o=yaml -l'",
+		"alias kgoyaml='kubectl --namespace=web get pods -o=yaml'",
+		"alias kgowidel='kubectl --namespace=web get pods -o=wide -l'",
+		"alias kgowide='kubectl --namespace=web get pods -o=wide'",
+		"alias kgojsonl='kubectl --namespace=web get pods -o=json -l'",
+		"alias kgojson='kubectl --namespace=web get pods -o=json'",
+		"alias kgsll='kubectl --namespace=web get pods --show-labels -l'",
+		"alias kgsl='kubectl --namespace=web get pods --show-labels'",
+		"alias kgwl='kubectl --namespace=web get pods --watch -l'",
+		"alias kgw='kubectl --namespace=web get pods --watch'",
+		"alias kgweventsl='kubectl --namespace=web get pods --watch --output-watch-events -l'",
+		"alias kgwevents='kubectl --namespace=web get pods --watch --output-watch-events'",
+		"alias kgl='kubectl --namespace=web get pods -l'",
+		"alias kg='kubectl --namespace=web get pods'",
+		"alias k='kubectl --namespace=web'",
+	}
+	if !reflect.DeepEqual(web, want) {
+		t.Errorf("web team:\ngot  %q\nwant %q", web, want)
+	}
+
+	payments, err := bundle(t, "payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"alias kgpo='kubectl --namespace=payments get pods'",
+		"alias kddep='kubectl --namespace=payments describe deployment'",
+		"alias krmsvc='kubectl --namespace=payments delete service'",
+		"alias kex='kubectl --namespace=payments exec -i -t'",
+		"alias klo='kubectl --namespace=payments logs -f'",
+	} {
+		if !slices.Contains(payments, want) {
+			t.Errorf("payments team: no %s", want)
+		}
+	}
+	for _, line := range payments {
+		name, command, _ := strings.Cut(strings.TrimPrefix(line, "alias "), "=")
+		fields := strings.Fields(strings.Trim(command, "'"))
+		if !slices.Equal(fields[:2], []string{"kubectl", "--namespace=payments"}) ||
+			slices.ContainsFunc(fields, func(field string) bool { return field == "--all-namespaces" || field == "--namespace" }) {
+			t.Errorf("payments team: %s picks another namespace: %s", name, command)
+		}
+		if len(fields) > 2 && !slices.Contains([]string{"get", "describe", "delete", "logs", "exec"}, fields[2]) {
+			t.Errorf("payments team: %s runs %s", name, fields[2])
+		}
+		if len(fields) > 3 && !slices.Contains([]string{"pods", "deployment", "service"}, fields[3]) && !strings.HasPrefix(fields[3], "-") {
+			t.Errorf("payments team: %s is for %s", name, fields[3])
+		}
+	}
+
+	for _, team := range []string{"missing", "bad-ns", "bad-res", "bad-op"} {
+		if _, err := bundle(t, team); err == nil {
+			t.Errorf("team %s: got no error", team)
+		}
+	}
+}
+
+func TestBuildAliasGeneratorMatchesLibrary(t *testing.T) {
+	// An importer passing the config to the library with the default options gets what kt aliases generates
+	config, err := aliases.ParseConfig([]byte("resources:\n  - alias: rs\n    full: replicasets\n    allowWhenOneOf: [g]\n"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag, err := buildAliasGenerator(config, "aliases.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := aliases.FromConfig(config, aliases.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ag.Generate(), g.Generate(); !reflect.DeepEqual(got, want) {
+		t.Errorf("kt aliases generates %d aliases, the library %d", len(got), len(want))
+	}
+}
+
+func TestCombineOps(t *testing.T) {
+	config := Config{Chains: []aliases.Chain{{Ops: []string{"g", "lo"}}}}
+	chains := func(t *testing.T, shell string) []string {
+		t.Helper()
+		withFlag(t, &aliasShell, shell)
+		ag, err := buildAliasGenerator(config, "chains.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := render(&ag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var chained []string
+		for _, line := range lines(string(out)) {
+			if strings.Contains(line, "logs -f") && strings.Contains(line, "get") {
+				chained = append(chained, line)
+			}
+		}
+		return chained
+	}
+
+	if got := chains(t, "bash"); len(got) > 0 {
+		t.Errorf("chains without --combine-ops: %q", got)
+	}
+	withFlag(t, &combineOps, true)
+	tests := map[string]string{
+		"bash":       `kglo() { kubectl get "$@" && kubectl logs -f "$@"; }`,
+		"fish":       "function kglo; kubectl get $argv; and kubectl logs -f $argv; end",
+		"powershell": "function kglo { kubectl get @args; if ($?) { kubectl logs -f @args } }",
+	}
+	for shell, want := range tests {
+		// The namespace shortcuts aren't chained, only the listed ops with the base command
+		if got := chains(t, shell); !reflect.DeepEqual(got, []string{want}) {
+			t.Errorf("%s: got %q, want %q", shell, got, want)
+		}
+	}
+
+	for _, rejected := range [][]string{{"g"}, {"g", "nope"}, {"g", "lo", "g"}} {
+		config := Config{Chains: []aliases.Chain{{Ops: rejected}}}
+		if _, err := buildAliasGenerator(config, "chains.yaml"); err == nil {
+			t.Errorf("chain %v: got no error", rejected)
+		}
+	}
+
+	// Denying an op drops the chains running it rather than failing
+	withFlag(t, &denyVerbs, []string{"lo"})
+	if got := chains(t, "bash"); len(got) > 0 {
+		t.Errorf("chains running a denied op: %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// conflictStrategies lists how an alias that shadows an existing name can be resolved
+var conflictStrategies = []string{"override", "skip", "prefix"}
+
+// existingAliasPattern matches alias definitions in bash, zsh and fish rc files, and fish and zsh-abbr abbreviations
+var existingAliasPattern = regexp.MustCompile(`^\s*(?:alias|abbr(?:\s+-a)?)\s+([A-Za-z0-9_.-]+)[=\s]`)
+
+// Conflicts holds the names the generated aliases shouldn't shadow and how to resolve an alias that does
+type Conflicts struct {
+	// Existing maps each taken name to what takes it, a binary's path or the file defining an alias
+	Existing map[string]string
+	// Strategy is one of conflictStrategies: override keeps the alias, skip drops it and prefix renames it
+	Strategy string
+	// Prefix is prepended to the name of a conflicting alias by the prefix strategy
+	Prefix string
+}
+
+// resolve reports each alias that shadows an existing name on stderr and applies the strategy to it
+func (c *Conflicts) resolve(aliases []Alias) []Alias {
+	var resolved []Alias
+	for _, alias := range aliases {
+		existing, conflicts := c.Existing[alias.Name]
+		if !conflicts {
+			resolved = append(resolved, alias)
+			continue
+		}
+
+		switch c.Strategy {
+		case "skip":
+			fmt.Fprintf(os.Stderr, "warning: skipping alias %s, which would shadow %s\n", alias.Name, existing)
+			continue
+		case "prefix":
+			fmt.Fprintf(os.Stderr, "warning: renaming alias %s to %s, which would shadow %s\n", alias.Name, c.Prefix+alias.Name, existing)
+			alias.Name = c.Prefix + alias.Name
+		default:
+			fmt.Fprintf(os.Stderr, "warning: alias %s shadows %s\n", alias.Name, existing)
+		}
+		resolved = append(resolved, alias)
+	}
+	return resolved
+}
+
+// loadConflicts collects the executables on the PATH and the aliases defined in each source, where a source
+// is a file path or - for stdin
+func loadConflicts(sources []string) (map[string]string, error) {
+	existing := pathExecutables(os.Getenv("PATH"))
+	for _, source := range sources {
+		var in io.Reader = os.Stdin
+		name := "stdin"
+		if source != "-" {
+			file, err := os.Open(source)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			in, name = file, source
+		}
+
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if match := existingAliasPattern.FindStringSubmatch(scanner.Text()); match != nil {
+				existing[match[1]] = "an alias in " + name
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+	}
+	return existing, nil
+}
+
+// pathExecutables maps the name of each executable in the PATH to its path, keeping the first one found
+// like the shell does. Directories that can't be read are skipped
+func pathExecutables(path string) map[string]string {
+	executables := make(map[string]string)
+	for _, dir := range filepath.SplitList(path) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if _, exists := executables[entry.Name()]; exists || entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || info.Mode()&0o111 == 0 {
+				continue
+			}
+			executables[entry.Name()] = "the executable " + filepath.Join(dir, entry.Name())
+		}
+	}
+	return executables
+}
+
+// conflictStrategyNames returns the strategies for use in messages
+func conflictStrategyNames() string {
+	return strings.Join(conflictStrategies, ", ")
+}
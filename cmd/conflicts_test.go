@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConflictsResolve(t *testing.T) {
+	generated := []Alias{
+		{Name: "kgpo", Command: "kubectl get pods"},
+		{Name: "kd", Command: "kubectl describe"},
+		{Name: "kdpo", Command: "kubectl describe pods"},
+	}
+	existing := map[string]string{"kd": "the executable /usr/bin/kd"}
+	tests := []struct {
+		strategy string
+		want     []string
+	}{
+		{"override", []string{"kgpo", "kd", "kdpo"}},
+		{"skip", []string{"kgpo", "kdpo"}},
+		// Only the alias that shadows a name is renamed, the others keep theirs
+		{"prefix", []string{"kgpo", "x_kd", "kdpo"}},
+	}
+	for _, test := range tests {
+		c := Conflicts{Existing: existing, Strategy: test.strategy, Prefix: "x_"}
+		var got []string
+		for _, alias := range c.resolve(generated) {
+			got = append(got, alias.Name)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.strategy, got, test.want)
+		}
+	}
+}
+
+func TestExistingAliasPattern(t *testing.T) {
+	tests := map[string]string{
+		"alias kgpo='kubectl get pods'": "kgpo",
+		"  alias k=kubectl":             "k",
+		"alias kgpo 'kubectl get pods'": "kgpo",
+		"abbr -a kgpo kubectl get pods": "kgpo",
+		"abbr kgpo='kubectl get pods'":  "kgpo",
+		"alias my.alias-1='ls'":         "my.alias-1",
+		"# alias kgpo='kubectl'":        "",
+		"export ALIAS=kgpo":             "",
+		"kgpo() { kubectl get pods; }":  "",
+	}
+	for line, want := range tests {
+		got := ""
+		if match := existingAliasPattern.FindStringSubmatch(line); match != nil {
+			got = match[1]
+		}
+		if got != want {
+			t.Errorf("%q: got %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestLoadConflicts(t *testing.T) {
+	bin := t.TempDir()
+	writeExecutable(t, filepath.Join(bin, "kgp"), 0o755)
+	t.Setenv("PATH", bin)
+	rcFile := filepath.Join(t.TempDir(), ".bashrc")
+	if err := os.WriteFile(rcFile, []byte("export EDITOR=vim\nalias kd='kubectl describe'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.WriteString("abbr -a kl kubectl logs\n")
+	writer.Close()
+	withFlag(t, &os.Stdin, reader)
+
+	got, err := loadConflicts([]string{rcFile, "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"kgp": "the executable " + filepath.Join(bin, "kgp"),
+		"kd":  "an alias in " + rcFile,
+		"kl":  "an alias in stdin",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := loadConflicts([]string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("got no error for a missing source")
+	}
+}
+
+func TestPathExecutables(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeExecutable(t, filepath.Join(first, "kgpo"), 0o755)
+	writeExecutable(t, filepath.Join(second, "kgpo"), 0o755)
+	writeExecutable(t, filepath.Join(second, "kd"), 0o755)
+	writeExecutable(t, filepath.Join(second, "README"), 0o644)
+	if err := os.Mkdir(filepath.Join(second, "kl"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first directory wins like in the shell, and files that can't run, directories and unreadable
+	// directories are left out
+	path := filepath.Join(t.TempDir(), "missing") + string(filepath.ListSeparator) + first + string(filepath.ListSeparator) + second
+	want := map[string]string{
+		"kgpo": "the executable " + filepath.Join(first, "kgpo"),
+		"kd":   "the executable " + filepath.Join(second, "kd"),
+	}
+	if got := pathExecutables(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// writeExecutable writes a script at path with the given mode
+func writeExecutable(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
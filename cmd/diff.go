@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+func init() {
+	aliasesCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <file>",
+	Short: "Shows the aliases added, removed and changed since a previously generated file",
+	Long: "Regenerates the aliases with the given flags and compares them, by name, against a file generated earlier" +
+		"\nin the same format, listing the aliases only the new output defines, those only the file defines and those" +
+		"\nwhose definition changed. Use it to review an upgrade or a flag change before re-sourcing.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		existing, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		generated, err := renderAliases()
+		if err != nil {
+			return err
+		}
+		fmt.Print(diffDefinitions(parseDefinitions(string(existing)), parseDefinitions(string(generated))))
+		return nil
+	},
+}
+
+// definitionPatterns match the name defined by a line in each output format and shell
+var definitionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\s*alias ([A-Za-z0-9_]+)[= ]`),
+	regexp.MustCompile(`^\s*([A-Za-z0-9_]+)\(\) \{`),
+	regexp.MustCompile(`^\s*function ([A-Za-z0-9_]+)[; ]`),
+	regexp.MustCompile(`^\s*abbr (?:-a )?([A-Za-z0-9_]+)[= ]`),
+	regexp.MustCompile(`^\s*\[([A-Za-z0-9_]+)\]=`),
+	regexp.MustCompile(`^"([A-Za-z0-9_]+)" = `),
+	regexp.MustCompile(`^set -as command-alias '([A-Za-z0-9_]+)=`),
+	regexp.MustCompile(`^([A-Za-z0-9_]+)\t`),
+	regexp.MustCompile(`^\s*\{"name":"([A-Za-z0-9_]+)"`),
+	regexp.MustCompile("^\\| `([A-Za-z0-9_]+)` \\|"),
+}
+
+// parseDefinitions maps the name of each alias defined in generated output to the trimmed line defining it,
+// skipping comments, guards and other lines that don't define an alias
+func parseDefinitions(output string) map[string]string {
+	definitions := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		for _, pattern := range definitionPatterns {
+			if match := pattern.FindStringSubmatch(line); match != nil {
+				definitions[match[1]] = strings.TrimSpace(line)
+				break
+			}
+		}
+	}
+	return definitions
+}
+
+// diffDefinitions lists the aliases only present in after with a '+' prefix, those only present in before with
+// a '-' prefix and those defined differently as the old line with '-' over the new one with '+', sorted by name
+func diffDefinitions(before, after map[string]string) string {
+	var added, removed, changed []string
+	for name, definition := range after {
+		previous, exists := before[name]
+		switch {
+		case !exists:
+			added = append(added, name)
+		case previous != definition:
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+	if len(added)+len(removed)+len(changed) == 0 {
+		return "no changes\n"
+	}
+
+	var diff strings.Builder
+	section := func(title string, names []string, write func(name string)) {
+		if len(names) == 0 {
+			return
+		}
+		slices.Sort(names)
+		fmt.Fprintf(&diff, "%s (%d):\n", title, len(names))
+		for _, name := range names {
+			write(name)
+		}
+	}
+	section("added", added, func(name string) { fmt.Fprintf(&diff, "  + %s\n", after[name]) })
+	section("removed", removed, func(name string) { fmt.Fprintf(&diff, "  - %s\n", before[name]) })
+	section("changed", changed, func(name string) {
+		fmt.Fprintf(&diff, "  - %s\n  + %s\n", before[name], after[name])
+	})
+	return diff.String()
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"os"
+	"slices"
+)
+
+func init() {
+	aliasesCmd.AddCommand(dumpResolvedConfigCmd)
+}
+
+var dumpResolvedConfigCmd = &cobra.Command{
+	Use:   "dump-resolved-config",
+	Short: "Prints the resolved parts as a config file",
+	Long: "Resolves the parts from the built-ins, the config file and the given flags, and prints them as a single" +
+		"\nYAML config that replaces every group, so loading it with --config reproduces the same alias set." +
+		"\nOutput options such as --format, --sort and --suffix aren't part of the config and still need passing.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ag, err := newAliasGenerator()
+		if err != nil {
+			return err
+		}
+		out, err := marshalConfig(ag.resolvedConfig())
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+// resolvedConfig returns a config that replaces every group with the generator's parts
+func (ag *AliasGenerator) resolvedConfig() Config {
+	config := Config{
+		Replace:     true,
+		Commands:    ag.Commands,
+		GlobalOps:   ag.GlobalOps,
+		Ops:         ag.Ops,
+		Resources:   ag.Resources,
+		Args:        ag.Args,
+		PosArgs:     ag.PosArgs,
+		Convenience: ag.Convenience,
+	}
+	for alias := range ag.Deprecated {
+		config.Deprecated = append(config.Deprecated, alias)
+	}
+	slices.Sort(config.Deprecated)
+	return config
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolvedConfigRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags func()
+		tools []string
+	}{
+		{name: "built-ins", flags: func() {}},
+		{name: "opt-in groups", flags: func() { includeCertManager, includeAuth, rbacAliases = true, true, true }},
+		{name: "every op denied", flags: func() { denyVerbs = aliases.ResourceTypes(aliases.Operations(rolloutTimeout)) }},
+		{name: "only resources", flags: func() { onlyResources = []string{"po", "svc"} }},
+		{name: "without kubectl", flags: func() {}, tools: []string{"helm"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previousCertManager, previousAuth, previousRBAC := includeCertManager, includeAuth, rbacAliases
+			previousDeny, previousOnly, previousTools := denyVerbs, onlyResources, aliasTools
+			resetFlags := func() {
+				includeCertManager, includeAuth, rbacAliases = previousCertManager, previousAuth, previousRBAC
+				denyVerbs, onlyResources = previousDeny, previousOnly
+			}
+			t.Cleanup(func() {
+				resetFlags()
+				aliasTools = previousTools
+			})
+			if test.tools != nil {
+				aliasTools = test.tools
+			}
+
+			test.flags()
+			ag, err := buildAliasGenerator(Config{}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ag.Generate()
+			dumped, err := marshalConfig(ag.resolvedConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The dumped config alone reproduces the aliases, without the flags that shaped the parts
+			resetFlags()
+			path := filepath.Join(t.TempDir(), "resolved.yaml")
+			if err := os.WriteFile(path, dumped, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := loadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := buildAliasGenerator(*config, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := loaded.Generate(); !reflect.DeepEqual(got, want) {
+				t.Errorf("loading the dumped config generates %d aliases, want the %d it was dumped from:\n%s", len(got), len(want), dumped)
+			}
+		})
+	}
+}
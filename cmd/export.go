@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"strings"
+)
+
+// jsonAlias is an alias as written by the json format
+type jsonAlias struct {
+	Name        string `json:"name"`
+	Command     string `json:"command"`
+	Operation   string `json:"operation,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Argument    string `json:"argument,omitempty"`
+	Description string `json:"description,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+	Function    bool   `json:"function,omitempty"`
+}
+
+// writeJSON writes the aliases as a JSON array with one alias object per line, so the output can be parsed as a
+// whole and still diffed line by line
+func (ag *AliasGenerator) writeJSON(aliases []Alias) error {
+	if len(aliases) == 0 {
+		fmt.Fprintln(ag.Out, "[]")
+		return nil
+	}
+	fmt.Fprintln(ag.Out, "[")
+	for i, alias := range aliases {
+		var line bytes.Buffer
+		encoder := json.NewEncoder(&line)
+		// Commands are shell, where &, < and > are common, not HTML
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(jsonAlias{
+			Name:        alias.Name,
+			Command:     alias.Command,
+			Operation:   alias.Operation,
+			Resource:    alias.Resource,
+			Argument:    alias.Argument,
+			Description: ag.Descriptions[alias.Resource],
+			Deprecated:  alias.Deprecated,
+			Function:    alias.Function,
+		})
+		if err != nil {
+			return err
+		}
+		separator := ","
+		if i == len(aliases)-1 {
+			separator = ""
+		}
+		fmt.Fprintf(ag.Out, "  %s%s\n", bytes.TrimSuffix(line.Bytes(), []byte("\n")), separator)
+	}
+	fmt.Fprintln(ag.Out, "]")
+	return nil
+}
+
+// writeMarkdown writes the aliases as a cheat sheet with a section per command, operation and resource, in the
+// order their first alias appears. Aliases without an operation come last, under Other, and aliases without a
+// resource first in their operation's section
+func (ag *AliasGenerator) writeMarkdown(aliases []Alias) {
+	fmt.Fprintln(ag.Out, "# Aliases")
+	displays := ag.resourceDisplays()
+	commands, byCommand := groupAliases(aliases, func(alias Alias) string {
+		return strings.Fields(ag.completedCommand(alias))[0]
+	})
+	for _, command := range commands {
+		fmt.Fprintf(ag.Out, "\n## %s\n", command)
+		operations, byOperation := groupAliases(byCommand[command], func(alias Alias) string { return alias.Operation })
+		for _, operation := range operations {
+			resources, byResource := groupAliases(byOperation[operation], func(alias Alias) string { return alias.Resource })
+			if operation == "" {
+				fmt.Fprintln(ag.Out, "\n### Other")
+			} else {
+				fmt.Fprintf(ag.Out, "\n### %s\n", markdownCode(operation))
+			}
+			if unscoped, ok := byResource[""]; ok {
+				ag.writeMarkdownTable(unscoped)
+			}
+			for _, resource := range resources {
+				if resource == "" {
+					continue
+				}
+				heading := resource
+				if display, ok := displays[resource]; ok {
+					heading = display
+				}
+				fmt.Fprintf(ag.Out, "\n#### %s\n", markdownCode(heading))
+				if description, ok := ag.Descriptions[resource]; ok {
+					fmt.Fprintf(ag.Out, "\n%s\n", description)
+				}
+				ag.writeMarkdownTable(byResource[resource])
+			}
+		}
+	}
+}
+
+// resourceDisplays maps the expansion of each resource that has a display name to that name, which the cheat
+// sheet heads the resource's section with while the commands keep the expansion
+func (ag *AliasGenerator) resourceDisplays() map[string]string {
+	displays := make(map[string]string)
+	generators := []*aliases.Generator{&ag.Generator}
+	for len(generators) > 0 {
+		g := generators[0]
+		generators = append(generators[1:], g.Tools...)
+		for _, resource := range g.Resources {
+			if resource.Display != "" {
+				displays[resource.Full] = resource.Display
+			}
+		}
+	}
+	return displays
+}
+
+// writeMarkdownTable writes a table of the aliases and their commands
+func (ag *AliasGenerator) writeMarkdownTable(aliases []Alias) {
+	fmt.Fprintln(ag.Out)
+	fmt.Fprintln(ag.Out, "| Alias | Command |")
+	fmt.Fprintln(ag.Out, "|-------|---------|")
+	for _, alias := range aliases {
+		// A pipe ends the cell even inside a code span unless it's escaped
+		command := strings.ReplaceAll(markdownCode(alias.Command), "|", `\|`)
+		if alias.Deprecated {
+			command += " (deprecated)"
+		}
+		fmt.Fprintf(ag.Out, "| %s | %s |\n", markdownCode(alias.Name), command)
+	}
+}
+
+// markdownCode returns s as a code span, delimited by one more backtick than the longest run of them in s so the
+// backticks of a command such as sh -c "`id`" are kept as they are
+func markdownCode(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	if longest == 0 {
+		return "`" + s + "`"
+	}
+	// The spaces keep a backtick at either end from joining the delimiter, and are stripped when rendered
+	delimiter := strings.Repeat("`", longest+1)
+	return delimiter + " " + s + " " + delimiter
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownCode(t *testing.T) {
+	tests := map[string]string{
+		"kubectl get pods":          "`kubectl get pods`",
+		"sh -c \"`id`\"":            "`` sh -c \"`id`\" ``",
+		"echo ``nested``":           "``` echo ``nested`` ```",
+		"`id`":                      "`` `id` ``",
+		"kubectl get pods -l 'a|b'": "`kubectl get pods -l 'a|b'`",
+	}
+	for s, want := range tests {
+		if got := markdownCode(s); got != want {
+			t.Errorf("markdownCode(%s) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestWriteMarkdownTable(t *testing.T) {
+	var out bytes.Buffer
+	ag := AliasGenerator{Out: &out}
+	ag.writeMarkdownTable([]Alias{
+		{Name: "kgpo", Command: "kubectl get pods"},
+		{Name: "kgpoj", Command: `kubectl get pods -o=jsonpath='{range .items[*]}{.a}|{.b}{end}'`},
+		{Name: "kex", Command: "kubectl exec -- sh -c \"`id`\""},
+		{Name: "kgcs", Command: "kubectl get componentstatuses", Deprecated: true},
+	})
+	want := "\n| Alias | Command |\n|-------|---------|\n" +
+		"| `kgpo` | `kubectl get pods` |\n" +
+		"| `kgpoj` | `kubectl get pods -o=jsonpath='{range .items[*]}{.a}\\|{.b}{end}'` |\n" +
+		"| `kex` | `` kubectl exec -- sh -c \"`id`\" `` |\n" +
+		"| `kgcs` | `kubectl get componentstatuses` (deprecated) |\n"
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	generated := []Alias{
+		{Name: "kgpo", Command: "kubectl get pods", Operation: "get", Resource: "pods"},
+		{Name: "kgpol", Command: `kubectl get pods -l "app in (a,b)" 2>&1 | grep -v '<none>' && echo \done`, Operation: "get", Resource: "pods"},
+		{Name: "krm", Command: "confirm kubectl delete", Operation: "delete", Function: true},
+	}
+	var out bytes.Buffer
+	ag := AliasGenerator{Out: &out}
+	ag.Descriptions = map[string]string{"pods": `Pod is a "collection" of containers`}
+	if err := ag.writeJSON(generated); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(generated)+2 {
+		t.Errorf("got %d lines, want one per alias between the brackets:\n%s", len(lines), out.String())
+	}
+	if strings.Contains(out.String(), `\u003c`) || strings.Contains(out.String(), `\u0026`) {
+		t.Errorf("shell characters are HTML-escaped:\n%s", out.String())
+	}
+
+	var parsed []jsonAlias
+	if err := json.Unmarshal(out.Bytes(), &parsed); err != nil {
+		t.Fatalf("output isn't valid JSON: %v\n%s", err, out.String())
+	}
+	want := []jsonAlias{
+		{Name: "kgpo", Command: "kubectl get pods", Operation: "get", Resource: "pods", Description: `Pod is a "collection" of containers`},
+		{Name: "kgpol", Command: generated[1].Command, Operation: "get", Resource: "pods", Description: `Pod is a "collection" of containers`},
+		{Name: "krm", Command: "confirm kubectl delete", Operation: "delete", Function: true},
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("got %+v, want %+v", parsed, want)
+	}
+
+	out.Reset()
+	if err := ag.writeJSON(nil); err != nil || out.String() != "[]\n" {
+		t.Errorf("no aliases are written as %q (%v), want an empty array", out.String(), err)
+	}
+}
+
+func TestMarkdownResourceDisplay(t *testing.T) {
+	var out bytes.Buffer
+	ag := AliasGenerator{Out: &out}
+	ag.Resources = []Part{
+		{Alias: "po", Full: "pods"},
+		{Alias: "dep", Full: "deployment", Display: "deployments"},
+	}
+	ag.writeMarkdown([]Alias{
+		{Name: "kgpo", Command: "kubectl get pods", Operation: "get", Resource: "pods"},
+		{Name: "kgdep", Command: "kubectl get deployment", Operation: "get", Resource: "deployment"},
+	})
+
+	// The heading reads deployments while the command keeps the expansion kubectl is given
+	for _, want := range []string{"#### `pods`\n", "#### `deployments`\n", "| `kgdep` | `kubectl get deployment` |"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("cheat sheet doesn't include %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "#### `deployment`") {
+		t.Errorf("cheat sheet heads a resource with its expansion despite a display name:\n%s", out.String())
+	}
+}
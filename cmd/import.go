@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	aliasesCmd.AddCommand(importCmd)
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Converts a kubectl-aliases file into a config",
+	Long: "Reads an alias file generated by ahmetb/kubectl-aliases and prints a config adding what the current parts" +
+		"\ndon't already generate. An alias one unknown part away from a combination of known parts gets that part" +
+		"\ninferred, limited to the operations it was seen with, and any other alias is kept as a raw convenience entry.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		legacy, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		ag, err := newAliasGenerator()
+		if err != nil {
+			return err
+		}
+		out, err := marshalConfig(ag.importAliases(parseLegacyAliases(string(legacy)), os.Stderr))
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+// legacyAliasPattern matches a bash alias definition, as kubectl-aliases writes them
+var legacyAliasPattern = regexp.MustCompile(`^\s*alias ([A-Za-z0-9_]+)=(.+)$`)
+
+// legacyAlias is an alias name and the command it expands to, read from a kubectl-aliases file
+type legacyAlias struct {
+	name    string
+	command string
+}
+
+// parseLegacyAliases returns the aliases defined in a kubectl-aliases file, in file order, skipping comments and
+// any other line that isn't an alias definition
+func parseLegacyAliases(file string) []legacyAlias {
+	var parsed []legacyAlias
+	for _, line := range strings.Split(file, "\n") {
+		match := legacyAliasPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		value := match[2]
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = strings.ReplaceAll(value[1:len(value)-1], `'\''`, `'`)
+		case len(value) >= 2 && value[0] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		if command := strings.Join(strings.Fields(value), " "); command != "" {
+			parsed = append(parsed, legacyAlias{name: match[1], command: command})
+		}
+	}
+	return parsed
+}
+
+// importAliases returns the config adding the parts needed to generate the legacy aliases. Parts are inferred
+// first, shortest alias first so that a part is known before the longer aliases combining it, then each alias the
+// resulting parts still don't generate with the same command is kept as a convenience entry, or reported on
+// warnings when it doesn't start with a command
+func (ag *AliasGenerator) importAliases(legacy []legacyAlias, warnings io.Writer) Config {
+	byLength := slices.Clone(legacy)
+	slices.SortStableFunc(byLength, func(a, b legacyAlias) int { return len(a.name) - len(b.name) })
+	var inferredStages []int
+	var inferred []Part
+	for _, alias := range byLength {
+		if stage, part, ok := ag.inferPart(alias, inferred); ok {
+			*ag.Stages()[stage] = append(*ag.Stages()[stage], part)
+			inferredStages = append(inferredStages, stage)
+			inferred = append(inferred, part)
+		}
+	}
+
+	// The inferred parts are taken back from their group, where later aliases may have extended their rules
+	config := Config{}
+	for i, stage := range inferredStages {
+		group := *ag.Stages()[stage]
+		part := group[slices.IndexFunc(group, func(p Part) bool { return samePart(p, inferred[i]) })]
+		switch stage {
+		case aliases.StageGlobalOps:
+			config.GlobalOps = append(config.GlobalOps, part)
+		case aliases.StageOps:
+			config.Ops = append(config.Ops, part)
+		case aliases.StageResources:
+			config.Resources = append(config.Resources, part)
+		case aliases.StageArgs:
+			config.Args = append(config.Args, part)
+		}
+	}
+
+	generated := make(map[string]string)
+	for _, alias := range ag.Generate() {
+		if _, exists := generated[alias.Name]; !exists {
+			generated[alias.Name] = alias.Command
+		}
+	}
+	for _, alias := range legacy {
+		command, exists := generated[alias.name]
+		if exists && command == alias.command {
+			continue
+		}
+		raw, ok := ag.rawEntry(alias)
+		if !ok {
+			fmt.Fprintf(warnings, "warning: skipping %s (%s), it doesn't start with a command\n", alias.name, alias.command)
+			continue
+		}
+		if exists {
+			fmt.Fprintf(warnings, "warning: %s is kept as a raw entry, but the generated %s runs %s first\n", alias.name, alias.name, command)
+		}
+		config.Convenience = append(config.Convenience, raw)
+	}
+	return config
+}
+
+// inferPart returns the part, and the stage of the group it belongs to, that completes the alias after the longest
+// prefix that known parts spell, when the rest of the command follows the prefix's command. An alias the known
+// parts already spell in full instead extends the AllowWhenOneOf rules of the inferred parts it combines
+func (ag *AliasGenerator) inferPart(alias legacyAlias, inferred []Part) (int, Part, bool) {
+	decompositions, _ := decompose(&ag.Generator, alias.name)
+	for _, parts := range decompositions {
+		if ag.NewAlias(parts).Command == alias.command {
+			ag.allowInferred(parts, inferred)
+			return 0, Part{}, false
+		}
+	}
+
+	for i := len(alias.name) - 1; i > 0; i-- {
+		prefixes, _ := decompose(&ag.Generator, alias.name[:i])
+		for _, parts := range prefixes {
+			rest, found := strings.CutPrefix(alias.command, ag.NewAlias(parts).Command+" ")
+			if !found {
+				continue
+			}
+			stage := ag.nextStage(parts, rest)
+			if stage < 0 {
+				continue
+			}
+			part := Part{Alias: alias.name[i:], Full: rest}
+			if slices.ContainsFunc(*ag.Stages()[stage], func(p Part) bool { return p.Alias == part.Alias }) {
+				continue
+			}
+			if stage == aliases.StageResources || stage == aliases.StageArgs {
+				if op := ag.partIn(parts, aliases.StageOps); op != nil {
+					part.AllowWhenOneOf = []string{op.Alias}
+				}
+			}
+			return stage, part, true
+		}
+	}
+	return 0, Part{}, false
+}
+
+// allowInferred adds the operation of the combination to the AllowWhenOneOf of each inferred part in it that has
+// such a rule, so the part keeps combining with every operation it was imported with
+func (ag *AliasGenerator) allowInferred(parts []Part, inferred []Part) {
+	op := ag.partIn(parts, aliases.StageOps)
+	if op == nil {
+		return
+	}
+	for _, part := range parts {
+		if !slices.ContainsFunc(inferred, func(p Part) bool { return samePart(p, part) }) {
+			continue
+		}
+		for _, group := range []*[]Part{&ag.Resources, &ag.Args} {
+			for i := range *group {
+				existing := &(*group)[i]
+				if samePart(*existing, part) && len(existing.AllowWhenOneOf) > 0 && !slices.Contains(existing.AllowWhenOneOf, op.Alias) {
+					existing.AllowWhenOneOf = append(existing.AllowWhenOneOf, op.Alias)
+				}
+			}
+		}
+	}
+}
+
+// nextStage returns the stage a part expanding to rest takes after the combination: a flag is a global op straight
+// after the command and an argument after the operation or resource, while a word is the operation after the
+// command or global op and the resource after the operation. It returns -1 where no part fits
+func (ag *AliasGenerator) nextStage(parts []Part, rest string) int {
+	flag := strings.HasPrefix(rest, "-")
+	switch ag.stageOf(parts[len(parts)-1]) {
+	case aliases.StageCommands:
+		if flag {
+			return aliases.StageGlobalOps
+		}
+		return aliases.StageOps
+	case aliases.StageGlobalOps:
+		if !flag {
+			return aliases.StageOps
+		}
+	case aliases.StageOps:
+		if flag {
+			return aliases.StageArgs
+		}
+		return aliases.StageResources
+	case aliases.StageResources:
+		if flag {
+			return aliases.StageArgs
+		}
+	}
+	return -1
+}
+
+// stageOf returns the stage of the group the part belongs to, or -1 if none
+func (ag *AliasGenerator) stageOf(part Part) int {
+	for stage, group := range ag.Stages() {
+		if slices.ContainsFunc(*group, func(p Part) bool { return samePart(p, part) }) {
+			return stage
+		}
+	}
+	return -1
+}
+
+// partIn returns the part of the combination from the stage's group, or nil if it has none
+func (ag *AliasGenerator) partIn(parts []Part, stage int) *Part {
+	for i, part := range parts {
+		if ag.stageOf(part) == stage {
+			return &parts[i]
+		}
+	}
+	return nil
+}
+
+// rawEntry returns the alias as a convenience part of the command it starts with, if any
+func (ag *AliasGenerator) rawEntry(alias legacyAlias) (Part, bool) {
+	for _, cmd := range ag.Commands {
+		name, nameFound := strings.CutPrefix(alias.name, cmd.Alias)
+		full, fullFound := strings.CutPrefix(alias.command, cmd.Full+" ")
+		if nameFound && fullFound && name != "" {
+			return Part{Alias: name, Full: full}, true
+		}
+	}
+	return Part{}, false
+}
+
+// samePart reports whether the two parts have the same alias and expansion
+func samePart(a, b Part) bool {
+	return a.Alias == b.Alias && a.Full == b.Full
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// legacySample is an excerpt of a kubectl-aliases file, with a few aliases edited in that the built-in parts don't
+// generate
+const legacySample = `# This command is used a LOT both below and in daily life
+alias k=kubectl
+
+# Execute a kubectl command against all namespaces
+alias kca='_kca(){ kubectl "$@" --all-namespaces;  unset -f _kca; }; _kca'
+
+alias kgpo='kubectl get pods'
+alias ksysgpo='kubectl --namespace=kube-system get pods'
+alias kgpooyaml='kubectl get pods -o=yaml'
+alias kgrs='kubectl get replicaset'
+alias kdrs='kubectl describe replicaset'
+alias kgrsowide='kubectl get replicaset -o=wide'
+alias kgpow='kubectl get pods --watch'
+alias kgdepsl='kubectl get deployment --show-labels'
+alias kgpoimg='kubectl get pods -o=jsonpath='\''{..image}'\'''
+alias kdpooyaml='kubectl describe pods -o=yaml'
+alias kpoop='echo nope'
+`
+
+func TestParseLegacyAliases(t *testing.T) {
+	got := parseLegacyAliases(legacySample)
+	if len(got) != 13 {
+		t.Fatalf("got %d aliases, want 13: %v", len(got), got)
+	}
+	if want := (legacyAlias{name: "k", command: "kubectl"}); got[0] != want {
+		t.Errorf("got %v for an unquoted alias, want %v", got[0], want)
+	}
+	if want := "kubectl get pods -o=jsonpath='{..image}'"; got[10].command != want {
+		t.Errorf("got %q for an alias with escaped quotes, want %q", got[10].command, want)
+	}
+}
+
+func TestImportAliases(t *testing.T) {
+	ag, err := buildAliasGenerator(Config{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings strings.Builder
+	config := ag.importAliases(parseLegacyAliases(legacySample), &warnings)
+
+	// rs is inferred from kgrs and picks up d from kdrs, so kgrsowide combines it with the built-in owide
+	wantResources := []Part{{Alias: "rs", Full: "replicaset", AllowWhenOneOf: []string{"g", "d"}}}
+	if !reflect.DeepEqual(config.Resources, wantResources) {
+		t.Errorf("got resources %+v, want %+v", config.Resources, wantResources)
+	}
+
+	wantArgs := []Part{{Alias: "img", Full: "-o=jsonpath='{..image}'", AllowWhenOneOf: []string{"g"}}}
+	if !reflect.DeepEqual(config.Args, wantArgs) {
+		t.Errorf("got args %+v, want %+v", config.Args, wantArgs)
+	}
+	if len(config.Ops) > 0 || len(config.GlobalOps) > 0 {
+		t.Errorf("got parts already covered by the built-ins: ops %+v, global ops %+v", config.Ops, config.GlobalOps)
+	}
+
+	// kdpooyaml is spelt by known parts that a rule keeps apart, so it's kept as it is under its command
+	wantRaw := []Part{{Alias: "dpooyaml", Full: "describe pods -o=yaml"}}
+	if !reflect.DeepEqual(config.Convenience, wantRaw) {
+		t.Errorf("got raw entries %+v, want %+v", config.Convenience, wantRaw)
+	}
+	for _, skipped := range []string{"skipping kca", "skipping kpoop"} {
+		if !strings.Contains(warnings.String(), skipped) {
+			t.Errorf("warnings don't include %q:\n%s", skipped, warnings.String())
+		}
+	}
+
+	// Loading the imported config generates every alias of the file that isn't skipped
+	imported, err := buildAliasGenerator(config, "imported.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := make(map[string]string)
+	for _, alias := range imported.Generate() {
+		generated[alias.Name] = alias.Command
+	}
+	for _, alias := range parseLegacyAliases(legacySample) {
+		if alias.name == "kca" || alias.name == "kpoop" {
+			continue
+		}
+		if got := generated[alias.name]; got != alias.command {
+			t.Errorf("%s: got %q, want %q", alias.name, got, alias.command)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	aliasesCmd.AddCommand(initConfigCmd)
+}
+
+var initConfigCmd = &cobra.Command{
+	Use:   "init-config",
+	Short: "Writes the built-in parts to a config file to start customizing from",
+	Long: "Writes the built-in parts, adjusted by the given flags, to ~/.kube-tools/aliases.yaml or the --config path" +
+		"\nas a config that replaces every group, ready to add or remove parts from. An existing file is left alone.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := configPath
+		if path == "-" {
+			return fmt.Errorf("can't write the config to stdin, pass a file path with --config")
+		}
+		if path == "" {
+			defaultPath, err := defaultConfigPath()
+			if err != nil {
+				return err
+			}
+			path = defaultPath
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+
+		ag, err := buildAliasGenerator(Config{}, "")
+		if err != nil {
+			return err
+		}
+		out, err := marshalConfig(ag.resolvedConfig())
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, out); err != nil {
+			return err
+		}
+		fmt.Printf("wrote %s\n", path)
+		return nil
+	},
+}
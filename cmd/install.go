@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// installMarker ends the line install adds to the rc file, so uninstall can find it again
+var installMarker = comment("added by kt aliases install")
+
+func init() {
+	aliasesCmd.AddCommand(installCmd)
+	aliasesCmd.AddCommand(uninstallCmd)
+}
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Writes the aliases to ~/.kube-tools and sources them from the shell's rc file",
+	Long: "Generates the aliases with the given flags, writes them to ~/.kube-tools/aliases.<shell> and adds a line" +
+		"\nsourcing that file to ~/.bashrc, ~/.zshrc or ~/.config/fish/config.fish. Running it again regenerates the" +
+		"\nfile without adding the line twice.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !isScriptFormat(aliasFormat) {
+			return fmt.Errorf("install only applies to the shell, assoc-array, zsh-abbr and fish-abbr formats")
+		}
+		// The rc file sources whatever is written, so a format the shell can't run would break every new shell
+		if err := formatShellError(aliasFormat, aliasShell); err != nil {
+			return err
+		}
+		aliasFile, rcFile, err := installPaths(aliasShell)
+		if err != nil {
+			return err
+		}
+
+		out, err := renderAliases()
+		if err != nil {
+			return err
+		}
+		mode, err := outputFileMode()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(aliasFile), 0o755); err != nil {
+			return err
+		}
+		if err := writeFileAtomicMode(aliasFile, out, mode); err != nil {
+			return err
+		}
+		fmt.Printf("wrote %s\n", aliasFile)
+
+		added, err := addSourceLine(rcFile, sourceLine(aliasShell, aliasFile))
+		if err != nil {
+			return err
+		}
+		if added {
+			fmt.Printf("added a line sourcing it to %s, open a new shell to load the aliases\n", rcFile)
+		}
+		return nil
+	},
+}
+
+var uninstallCmd = &cobra.Command{
+	Use:          "uninstall",
+	Short:        "Removes the aliases written by install and the line sourcing them",
+	Long:         "Removes ~/.kube-tools/aliases.<shell> and the line install added to the shell's rc file.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aliasFile, rcFile, err := installPaths(aliasShell)
+		if err != nil {
+			return err
+		}
+		if err := removeSourceLine(rcFile); err != nil {
+			return err
+		}
+		if err := os.Remove(aliasFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		fmt.Printf("removed %s and its line in %s\n", aliasFile, rcFile)
+		return nil
+	},
+}
+
+// installPaths returns where install writes the aliases for the shell and the rc file it sources them from
+func installPaths(shell string) (string, string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", err
+	}
+	aliasFile := filepath.Join(home, ".kube-tools", "aliases."+shell)
+	switch shell {
+	case "bash":
+		return aliasFile, filepath.Join(home, ".bashrc"), nil
+	case "zsh":
+		zdotdir := os.Getenv("ZDOTDIR")
+		if zdotdir == "" {
+			zdotdir = home
+		}
+		return aliasFile, filepath.Join(zdotdir, ".zshrc"), nil
+	case "fish":
+		return aliasFile, filepath.Join(home, ".config", "fish", "config.fish"), nil
+	default:
+		return "", "", fmt.Errorf("install supports bash, zsh and fish, not %s", shell)
+	}
+}
+
+// sourceLine returns the rc file line that sources the alias file, ending in installMarker
+func sourceLine(shell string, aliasFile string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("test -f %[1]s; and source %[1]s %[2]s", singleQuote(shell, aliasFile), installMarker)
+	}
+	return fmt.Sprintf("[ -f %[1]s ] && . %[1]s %[2]s", singleQuote(shell, aliasFile), installMarker)
+}
+
+// addSourceLine appends line to the rc file, creating it if needed, unless the file already has it.
+// It reports whether the line was added
+func addSourceLine(rcFile string, line string) (bool, error) {
+	rcFile = resolveLink(rcFile)
+	existing, err := os.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	for _, current := range strings.Split(string(existing), "\n") {
+		if current == line {
+			return false, nil
+		}
+	}
+
+	content := string(existing)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if err := os.MkdirAll(filepath.Dir(rcFile), 0o755); err != nil {
+		return false, err
+	}
+	return true, writeFileAtomic(rcFile, []byte(content+line+"\n"))
+}
+
+// removeSourceLine drops every line ending in installMarker from the rc file, leaving the rest untouched
+func removeSourceLine(rcFile string) error {
+	rcFile = resolveLink(rcFile)
+	existing, err := os.ReadFile(rcFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var kept []string
+	for _, line := range strings.SplitAfter(string(existing), "\n") {
+		if !strings.HasSuffix(strings.TrimRight(line, "\n"), installMarker) {
+			kept = append(kept, line)
+		}
+	}
+	return writeFileAtomic(rcFile, []byte(strings.Join(kept, "")))
+}
+
+// resolveLink returns the file a symlink points at, so rewriting a dotfile manager's linked rc file updates
+// the target instead of replacing the link
+func resolveLink(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSourceLine(t *testing.T) {
+	tests := map[string]string{
+		"bash": "[ -f '/home/a b/.kube-tools/aliases.bash' ] && . '/home/a b/.kube-tools/aliases.bash' " + installMarker,
+		"fish": "test -f '/home/a b/.kube-tools/aliases.fish'; and source '/home/a b/.kube-tools/aliases.fish' " + installMarker,
+	}
+	for shell, want := range tests {
+		if got := sourceLine(shell, "/home/a b/.kube-tools/aliases."+shell); got != want {
+			t.Errorf("%s:\ngot  %s\nwant %s", shell, got, want)
+		}
+	}
+}
+
+func TestAddSourceLine(t *testing.T) {
+	rcFile := filepath.Join(t.TempDir(), ".bashrc")
+	if err := os.WriteFile(rcFile, []byte("export EDITOR=vim"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	line := sourceLine("bash", "/home/me/.kube-tools/aliases.bash")
+
+	// Running install again doesn't add the line twice
+	for i, want := range []bool{true, false} {
+		added, err := addSourceLine(rcFile, line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != want {
+			t.Errorf("run %d: got added %v, want %v", i+1, added, want)
+		}
+	}
+	got, err := os.ReadFile(rcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "export EDITOR=vim\n" + line + "\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// A missing rc file is created, along with its directory
+	fishConfig := filepath.Join(t.TempDir(), ".config", "fish", "config.fish")
+	if added, err := addSourceLine(fishConfig, line); err != nil || !added {
+		t.Errorf("creating %s: got added %v, %v", fishConfig, added, err)
+	}
+}
+
+func TestRemoveSourceLine(t *testing.T) {
+	rcFile := filepath.Join(t.TempDir(), ".zshrc")
+	// The line is found by its marker, even when it sources a file from an older home
+	rc := "export EDITOR=vim\n" +
+		sourceLine("zsh", "/old/home/.kube-tools/aliases.zsh") + "\n" +
+		"# kt aliases install is run from the dotfiles\n" +
+		"alias ll='ls -l'"
+	if err := os.WriteFile(rcFile, []byte(rc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeSourceLine(rcFile); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(rcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "export EDITOR=vim\n# kt aliases install is run from the dotfiles\nalias ll='ls -l'"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err := removeSourceLine(filepath.Join(t.TempDir(), ".bashrc")); err != nil {
+		t.Errorf("got %v for a missing rc file", err)
+	}
+}
+
+func TestSourceLineThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "bashrc")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("export EDITOR=vim\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rcFile := filepath.Join(dir, ".bashrc")
+	if err := os.Symlink(target, rcFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link is kept and its target rewritten, so the dotfile manager still tracks the change
+	line := sourceLine("bash", "/home/me/.kube-tools/aliases.bash")
+	if _, err := addSourceLine(rcFile, line); err != nil {
+		t.Fatal(err)
+	}
+	assertLinkedContent(t, rcFile, target, "export EDITOR=vim\n"+line+"\n")
+	if err := removeSourceLine(rcFile); err != nil {
+		t.Fatal(err)
+	}
+	assertLinkedContent(t, rcFile, target, "export EDITOR=vim\n")
+}
+
+// assertLinkedContent checks that link is still a symlink and that target holds want
+func assertLinkedContent(t *testing.T, link, target, want string) {
+	t.Helper()
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%s is no longer a symlink: %v", link, err)
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q in the link target, want %q", got, want)
+	}
+}
+
+func TestInstallPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ZDOTDIR", "")
+	tests := map[string]string{
+		"bash": filepath.Join(home, ".bashrc"),
+		"zsh":  filepath.Join(home, ".zshrc"),
+		"fish": filepath.Join(home, ".config", "fish", "config.fish"),
+	}
+	for shell, want := range tests {
+		aliasFile, rcFile, err := installPaths(shell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rcFile != want || aliasFile != filepath.Join(home, ".kube-tools", "aliases."+shell) {
+			t.Errorf("%s: got %s and %s", shell, aliasFile, rcFile)
+		}
+	}
+
+	// zsh reads its rc file from ZDOTDIR when it's set
+	zdotdir := filepath.Join(home, ".config", "zsh")
+	t.Setenv("ZDOTDIR", zdotdir)
+	if _, rcFile, err := installPaths("zsh"); err != nil || rcFile != filepath.Join(zdotdir, ".zshrc") {
+		t.Errorf("got %s, %v with ZDOTDIR set", rcFile, err)
+	}
+	if _, _, err := installPaths("powershell"); err == nil {
+		t.Error("got no error for powershell")
+	}
+}
+
+func TestInstallRejectsFormatForOtherShell(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withFlag(t, &aliasFormat, "zsh-abbr")
+	withFlag(t, &aliasShell, "bash")
+	if err := installCmd.RunE(installCmd, nil); err == nil || !strings.Contains(err.Error(), "requires --shell zsh") {
+		t.Errorf("got %v", err)
+	}
+	if entries, _ := os.ReadDir(home); len(entries) > 0 {
+		t.Errorf("wrote %d files before rejecting the format", len(entries))
+	}
+}
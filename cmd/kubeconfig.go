@@ -0,0 +1,466 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// kubeconfigPathFields are the fields of clusters and users naming a file, which kubectl resolves relative to the
+// kubeconfig they're in
+var kubeconfigPathFields = map[string][]string{
+	"cluster": {"certificate-authority"},
+	"user":    {"client-certificate", "client-key", "tokenFile"},
+}
+
+// kubeconfigDefaultPorts are the ports dialed for a server or proxy URL without one, by scheme
+var kubeconfigDefaultPorts = map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}
+
+var (
+	// kubeconfigOutput is the file merge writes to, instead of stdout
+	kubeconfigOutput string
+	// kubeconfigTimeout is how long prune waits for a cluster's server to accept a connection
+	kubeconfigTimeout time.Duration
+	// kubeconfigDryRun lists the contexts prune would remove without removing them
+	kubeconfigDryRun bool
+	// kubeconfigYes removes the contexts without asking for confirmation
+	kubeconfigYes bool
+)
+
+func init() {
+	rootCmd.AddCommand(kubeconfigCmd)
+	kubeconfigCmd.AddCommand(kubeconfigMergeCmd)
+	kubeconfigCmd.AddCommand(kubeconfigPruneCmd)
+	kubeconfigMergeCmd.Flags().StringVarP(&kubeconfigOutput, "output", "o", "", "File to write the merged kubeconfig to (default stdout)")
+	kubeconfigPruneCmd.Flags().DurationVar(&kubeconfigTimeout, "timeout", 5*time.Second, "How long to wait for each cluster's server to accept a connection")
+	kubeconfigPruneCmd.Flags().BoolVar(&kubeconfigDryRun, "dry-run", false, "List the contexts that would be removed without removing them")
+	kubeconfigPruneCmd.Flags().BoolVarP(&kubeconfigYes, "yes", "y", false, "Remove without asking for confirmation")
+}
+
+var kubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig",
+	Short: "Merges kubeconfig files and prunes contexts of clusters that are gone",
+}
+
+var kubeconfigMergeCmd = &cobra.Command{
+	Use:   "merge <file...>",
+	Short: "Merges kubeconfig files into one",
+	Long: "Merges the clusters, users and contexts of the files into one kubeconfig, written to stdout or --output." +
+		"\nAn entry identical to one already merged is dropped, and a cluster or user identical to one merged under" +
+		"\nanother name is replaced by it. An entry whose name is taken by a different one is renamed with the name" +
+		"\nof its file appended, e.g. kind-kind-dev, and the contexts using it follow. Relative certificate and token" +
+		"\npaths are made absolute, and the current context is the first file's.",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		merged := &kubeconfig{APIVersion: "v1", Kind: "Config"}
+		for _, path := range args {
+			config, err := loadKubeconfig(path)
+			if err != nil {
+				return err
+			}
+			merged.merge(config, path)
+		}
+		out, err := marshalKubeconfig(merged)
+		if err != nil {
+			return err
+		}
+		if kubeconfigOutput == "" {
+			_, err := os.Stdout.Write(out)
+			return err
+		}
+		if err := writeFileAtomicMode(kubeconfigOutput, out, 0o600); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "merged %d contexts from %d files into %s\n", len(merged.Contexts), len(args), kubeconfigOutput)
+		return nil
+	},
+}
+
+var kubeconfigPruneCmd = &cobra.Command{
+	Use:   "prune [file]",
+	Short: "Removes contexts whose cluster is undefined or unreachable",
+	Long: "Removes the contexts of the kubeconfig, by default $KUBECONFIG or ~/.kube/config, whose cluster isn't" +
+		"\ndefined or whose server doesn't accept a connection within --timeout, after listing them and asking" +
+		"\nfor confirmation. Clusters and users only the removed contexts used are removed with them. Only the" +
+		"\nconnection is tried, no credentials are used, so a cluster that rejects the user is kept.",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := kubeconfigPath(args)
+		if err != nil {
+			return err
+		}
+		config, err := loadKubeconfig(path)
+		if err != nil {
+			return err
+		}
+		stale := findStaleContexts(config, kubeconfigTimeout)
+		if len(stale) == 0 {
+			fmt.Println("every context's cluster is reachable")
+			return nil
+		}
+
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "CONTEXT\tCLUSTER\tREASON")
+		for _, context := range config.Contexts {
+			if reason, ok := stale[context.Name]; ok {
+				fmt.Fprintf(table, "%s\t%s\t%s\n", context.Name, context.reference("cluster"), reason)
+			}
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+		if kubeconfigDryRun {
+			return nil
+		}
+		if !kubeconfigYes && !confirm(fmt.Sprintf("Remove these %d contexts from %s?", len(stale), path)) {
+			return nil
+		}
+
+		config.removeContexts(stale)
+		out, err := marshalKubeconfig(config)
+		if err != nil {
+			return err
+		}
+		// A kubeconfig linked in from elsewhere is rewritten where it lives, keeping the link
+		if err := writeFileAtomicMode(resolveLink(path), out, 0o600); err != nil {
+			return err
+		}
+		fmt.Printf("removed %d contexts from %s\n", len(stale), path)
+		return nil
+	},
+}
+
+// kubeconfig holds a kubeconfig file, keeping the fields kube-tools doesn't look at as they were
+type kubeconfig struct {
+	APIVersion     string            `yaml:"apiVersion"`
+	Kind           string            `yaml:"kind"`
+	Preferences    map[string]any    `yaml:"preferences"`
+	Clusters       []kubeconfigEntry `yaml:"clusters"`
+	Users          []kubeconfigEntry `yaml:"users"`
+	Contexts       []kubeconfigEntry `yaml:"contexts"`
+	CurrentContext string            `yaml:"current-context"`
+	Extensions     []any             `yaml:"extensions,omitempty"`
+	// Rest holds the top-level keys kube-tools doesn't know, so they're written back as they were
+	Rest map[string]any `yaml:",inline"`
+}
+
+// kubeconfigEntry is a named cluster, user or context, with its settings under the cluster, user or context key
+type kubeconfigEntry struct {
+	Name   string         `yaml:"name"`
+	Fields map[string]any `yaml:",inline"`
+}
+
+// settings returns the entry's settings under key
+func (e kubeconfigEntry) settings(key string) map[string]any {
+	settings, _ := e.Fields[key].(map[string]any)
+	return settings
+}
+
+// reference returns the name of the cluster or user a context refers to
+func (e kubeconfigEntry) reference(key string) string {
+	name, _ := e.settings("context")[key].(string)
+	return name
+}
+
+// loadKubeconfig reads a kubeconfig file, an empty one having no entries
+func loadKubeconfig(path string) (*kubeconfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &kubeconfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// marshalKubeconfig renders the kubeconfig as YAML, with an empty preferences map where kubectl would write one
+func marshalKubeconfig(config *kubeconfig) ([]byte, error) {
+	if config.Preferences == nil {
+		config.Preferences = map[string]any{}
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// kubeconfigPath returns the file given, else the single file $KUBECONFIG names, else ~/.kube/config
+func kubeconfigPath(args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	if files := filepath.SplitList(os.Getenv("KUBECONFIG")); len(files) > 1 {
+		return "", fmt.Errorf("KUBECONFIG names %d files, pass the one to prune", len(files))
+	} else if len(files) == 1 && files[0] != "" {
+		return files[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// merge adds the entries of the kubeconfig read from path, renaming those whose name is already taken
+func (c *kubeconfig) merge(config *kubeconfig, path string) {
+	dir := filepath.Dir(path)
+	suffix := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	clusters := mergeEntries(&c.Clusters, resolvePaths(config.Clusters, "cluster", dir), "cluster", path, suffix, true)
+	users := mergeEntries(&c.Users, resolvePaths(config.Users, "user", dir), "user", path, suffix, true)
+
+	// The contexts are compared after pointing them at the merged names of their cluster and user
+	for i, context := range config.Contexts {
+		settings := make(map[string]any)
+		for key, value := range context.settings("context") {
+			settings[key] = value
+		}
+		if renamed, ok := clusters[context.reference("cluster")]; ok {
+			settings["cluster"] = renamed
+		}
+		if renamed, ok := users[context.reference("user")]; ok {
+			settings["user"] = renamed
+		}
+		config.Contexts[i] = withSettings(context, "context", settings)
+	}
+	contexts := mergeEntries(&c.Contexts, config.Contexts, "context", path, suffix, false)
+
+	if c.CurrentContext == "" && config.CurrentContext != "" {
+		c.CurrentContext = contexts[config.CurrentContext]
+	}
+	if len(c.Preferences) == 0 {
+		c.Preferences = config.Preferences
+	}
+	c.Extensions = append(c.Extensions, config.Extensions...)
+	for key, value := range config.Rest {
+		if _, ok := c.Rest[key]; !ok {
+			if c.Rest == nil {
+				c.Rest = make(map[string]any)
+			}
+			c.Rest[key] = value
+		}
+	}
+}
+
+// mergeEntries appends the entries to merged and returns the name each one ended up with. An entry identical to
+// a merged one is dropped, as is one with the same settings under another name when byContent is set, and an entry
+// whose name is taken by a different one is renamed with the suffix
+func mergeEntries(merged *[]kubeconfigEntry, entries []kubeconfigEntry, key, path, suffix string, byContent bool) map[string]string {
+	names := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if same, ok := findSameEntry(*merged, entry, key, byContent); ok {
+			if same != entry.Name {
+				fmt.Fprintf(os.Stderr, "%s %s of %s is the same as %s, using that\n", key, entry.Name, path, same)
+			}
+			names[entry.Name] = same
+			continue
+		}
+		name := entry.Name
+		if containsEntry(*merged, name) {
+			name = uniqueEntryName(*merged, entry.Name+"-"+suffix)
+			fmt.Fprintf(os.Stderr, "renamed %s %s of %s to %s\n", key, entry.Name, path, name)
+		}
+		names[entry.Name] = name
+		entry.Name = name
+		*merged = append(*merged, entry)
+	}
+	return names
+}
+
+// findSameEntry returns the name of the merged entry identical to entry, preferring one with the same name, or
+// with byContent any with the same settings
+func findSameEntry(merged []kubeconfigEntry, entry kubeconfigEntry, key string, byContent bool) (string, bool) {
+	for _, existing := range merged {
+		if existing.Name == entry.Name && reflect.DeepEqual(existing.settings(key), entry.settings(key)) {
+			return existing.Name, true
+		}
+	}
+	if byContent {
+		for _, existing := range merged {
+			if reflect.DeepEqual(existing.settings(key), entry.settings(key)) {
+				return existing.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// containsEntry reports whether one of the entries has the name
+func containsEntry(entries []kubeconfigEntry, name string) bool {
+	for _, entry := range entries {
+		if entry.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueEntryName returns name, or name with the lowest number appended that none of the entries has
+func uniqueEntryName(entries []kubeconfigEntry, name string) string {
+	candidate := name
+	for i := 2; containsEntry(entries, candidate); i++ {
+		candidate = name + "-" + strconv.Itoa(i)
+	}
+	return candidate
+}
+
+// resolvePaths makes the relative file paths in the entries' settings absolute, relative to dir
+func resolvePaths(entries []kubeconfigEntry, key, dir string) []kubeconfigEntry {
+	resolved := make([]kubeconfigEntry, 0, len(entries))
+	for _, entry := range entries {
+		settings := make(map[string]any)
+		for field, value := range entry.settings(key) {
+			settings[field] = value
+		}
+		for _, field := range kubeconfigPathFields[key] {
+			if path, ok := settings[field].(string); ok && path != "" && !filepath.IsAbs(path) {
+				if absolute, err := filepath.Abs(filepath.Join(dir, path)); err == nil {
+					settings[field] = absolute
+				}
+			}
+		}
+		resolved = append(resolved, withSettings(entry, key, settings))
+	}
+	return resolved
+}
+
+// withSettings returns a copy of the entry with its settings under key replaced
+func withSettings(entry kubeconfigEntry, key string, settings map[string]any) kubeconfigEntry {
+	fields := make(map[string]any, len(entry.Fields))
+	for field, value := range entry.Fields {
+		fields[field] = value
+	}
+	fields[key] = settings
+	return kubeconfigEntry{Name: entry.Name, Fields: fields}
+}
+
+// findStaleContexts returns why each context whose cluster is undefined or unreachable should be removed, trying
+// each cluster's server once and all of them at the same time
+func findStaleContexts(config *kubeconfig, timeout time.Duration) map[string]string {
+	clusters := make(map[string]map[string]any, len(config.Clusters))
+	for _, cluster := range config.Clusters {
+		clusters[cluster.Name] = cluster.settings("cluster")
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	unreachable := make(map[string]string)
+	for name, settings := range clusters {
+		wg.Add(1)
+		go func(name string, settings map[string]any) {
+			defer wg.Done()
+			if err := dialCluster(settings, timeout); err != nil {
+				mu.Lock()
+				unreachable[name] = err.Error()
+				mu.Unlock()
+			}
+		}(name, settings)
+	}
+	wg.Wait()
+
+	stale := make(map[string]string)
+	for _, context := range config.Contexts {
+		cluster := context.reference("cluster")
+		if _, ok := clusters[cluster]; !ok {
+			stale[context.Name] = fmt.Sprintf("cluster %q isn't defined", cluster)
+		} else if reason, ok := unreachable[cluster]; ok {
+			stale[context.Name] = reason
+		}
+	}
+	return stale
+}
+
+// dialCluster opens and closes a connection to the cluster's server, or to its proxy when it has one
+func dialCluster(settings map[string]any, timeout time.Duration) error {
+	host, err := dialAddress(settings)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return fmt.Errorf("%s is unreachable: %w", host, err)
+	}
+	conn.Close()
+	return nil
+}
+
+// dialAddress returns the host and port of the cluster's server, or of its proxy when it has one, defaulting the
+// port by the URL's scheme
+func dialAddress(settings map[string]any) (string, error) {
+	server, _ := settings["server"].(string)
+	if server == "" {
+		return "", fmt.Errorf("the cluster has no server")
+	}
+	address := server
+	if proxy, _ := settings["proxy-url"].(string); proxy != "" {
+		address = proxy
+	}
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid server address %q", address)
+	}
+	if parsed.Port() != "" {
+		return parsed.Host, nil
+	}
+	port, ok := kubeconfigDefaultPorts[parsed.Scheme]
+	if !ok {
+		return "", fmt.Errorf("unknown scheme %q in %q, add the port to dial", parsed.Scheme, address)
+	}
+	return net.JoinHostPort(parsed.Hostname(), port), nil
+}
+
+// removeContexts removes the contexts along with the clusters and users that only they used
+func (c *kubeconfig) removeContexts(names map[string]string) {
+	var kept []kubeconfigEntry
+	orphaned := map[string]map[string]bool{"cluster": {}, "user": {}}
+	for _, context := range c.Contexts {
+		if _, remove := names[context.Name]; remove {
+			orphaned["cluster"][context.reference("cluster")] = true
+			orphaned["user"][context.reference("user")] = true
+		} else {
+			kept = append(kept, context)
+		}
+	}
+	for _, context := range kept {
+		delete(orphaned["cluster"], context.reference("cluster"))
+		delete(orphaned["user"], context.reference("user"))
+	}
+
+	c.Contexts = kept
+	c.Clusters = removeEntries(c.Clusters, orphaned["cluster"])
+	c.Users = removeEntries(c.Users, orphaned["user"])
+	if _, removed := names[c.CurrentContext]; removed {
+		c.CurrentContext = ""
+	}
+}
+
+// removeEntries returns the entries whose name isn't in names
+func removeEntries(entries []kubeconfigEntry, names map[string]bool) []kubeconfigEntry {
+	var kept []kubeconfigEntry
+	for _, entry := range entries {
+		if !names[entry.Name] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeKubeconfig writes content to a file named name in dir and returns its path
+func writeKubeconfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// entryNames returns the names of the entries in order
+func entryNames(entries []kubeconfigEntry) []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+func TestDialAddress(t *testing.T) {
+	tests := []struct {
+		server  string
+		proxy   string
+		want    string
+		wantErr bool
+	}{
+		{server: "https://10.0.0.1:6443", want: "10.0.0.1:6443"},
+		{server: "https://api.example.com", want: "api.example.com:443"},
+		{server: "http://localhost", want: "localhost:80"},
+		{server: "https://[fd00::1]", want: "[fd00::1]:443"},
+		{server: "https://api.example.com", proxy: "http://proxy.example.com:3128", want: "proxy.example.com:3128"},
+		{server: "https://api.example.com", proxy: "http://proxy.example.com", want: "proxy.example.com:80"},
+		{server: "https://api.example.com", proxy: "socks5://localhost", want: "localhost:1080"},
+		{server: "https://api.example.com", proxy: "socks5h://bastion", want: "bastion:1080"},
+		{server: "https://api.example.com", proxy: "ftp://proxy", wantErr: true},
+		{server: "", wantErr: true},
+		{server: "api.example.com", wantErr: true},
+	}
+	for _, test := range tests {
+		settings := map[string]any{"server": test.server, "proxy-url": test.proxy}
+		got, err := dialAddress(settings)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("server %q with proxy %q dials %s, want an error", test.server, test.proxy, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("server %q with proxy %q dials %s (%v), want %s", test.server, test.proxy, got, err, test.want)
+		}
+	}
+}
+
+func TestKubeconfigKeepsUnknownKeys(t *testing.T) {
+	path := writeKubeconfig(t, t.TempDir(), "config", `apiVersion: v1
+kind: Config
+clusters: []
+users: []
+contexts: []
+current-context: ""
+x-managed-by: cloud-cli
+future-field:
+  nested: true
+`)
+	config, err := loadKubeconfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := marshalKubeconfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"x-managed-by: cloud-cli\n", "future-field:\n  nested: true\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("the written kubeconfig lost %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestKubeconfigMerge(t *testing.T) {
+	dir := t.TempDir()
+	first := writeKubeconfig(t, dir, "prod.yaml", `clusters:
+- name: kind
+  cluster:
+    server: https://prod:6443
+users:
+- name: admin
+  user:
+    token: prod-token
+contexts:
+- name: kind
+  context:
+    cluster: kind
+    user: admin
+current-context: kind
+x-team: platform
+`)
+	second := writeKubeconfig(t, dir, "dev.yaml", `clusters:
+- name: kind
+  cluster:
+    server: https://dev:6443
+    certificate-authority: ca.crt
+users:
+- name: prod-admin
+  user:
+    token: prod-token
+contexts:
+- name: kind
+  context:
+    cluster: kind
+    user: prod-admin
+current-context: kind
+x-team: ignored
+`)
+
+	merged := &kubeconfig{}
+	for _, path := range []string{first, second} {
+		config, err := loadKubeconfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		merged.merge(config, path)
+	}
+
+	if got, want := entryNames(merged.Clusters), []string{"kind", "kind-dev"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("clusters are %v, want %v", got, want)
+	}
+	// The second file's user has the same token as the first's, so it's merged into it
+	if got, want := entryNames(merged.Users), []string{"admin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("users are %v, want %v", got, want)
+	}
+	if got, want := entryNames(merged.Contexts), []string{"kind", "kind-dev"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contexts are %v, want %v", got, want)
+	}
+	renamed := merged.Contexts[1]
+	if cluster, user := renamed.reference("cluster"), renamed.reference("user"); cluster != "kind-dev" || user != "admin" {
+		t.Errorf("renamed context uses cluster %s and user %s, want kind-dev and admin", cluster, user)
+	}
+	if ca := merged.Clusters[1].settings("cluster")["certificate-authority"]; ca != filepath.Join(dir, "ca.crt") {
+		t.Errorf("certificate authority is %v, want it relative to the file", ca)
+	}
+	if merged.CurrentContext != "kind" {
+		t.Errorf("current context is %q, want the first file's", merged.CurrentContext)
+	}
+	if got := merged.Rest["x-team"]; got != "platform" {
+		t.Errorf("unknown key x-team is %v, want the first file's", got)
+	}
+}
+
+func TestRemoveContexts(t *testing.T) {
+	context := func(name, cluster, user string) kubeconfigEntry {
+		return kubeconfigEntry{Name: name, Fields: map[string]any{"context": map[string]any{"cluster": cluster, "user": user}}}
+	}
+	config := &kubeconfig{
+		Clusters: []kubeconfigEntry{{Name: "gone"}, {Name: "shared"}, {Name: "live"}},
+		Users:    []kubeconfigEntry{{Name: "gone-admin"}, {Name: "admin"}},
+		Contexts: []kubeconfigEntry{
+			context("gone", "gone", "gone-admin"),
+			context("shared-old", "shared", "admin"),
+			context("shared-new", "shared", "admin"),
+			context("live", "live", "admin"),
+		},
+		CurrentContext: "gone",
+	}
+	config.removeContexts(map[string]string{"gone": "unreachable", "shared-old": "unreachable"})
+
+	if got, want := entryNames(config.Contexts), []string{"shared-new", "live"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("contexts are %v, want %v", got, want)
+	}
+	// Only the clusters and users no remaining context uses are removed
+	if got, want := entryNames(config.Clusters), []string{"shared", "live"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("clusters are %v, want %v", got, want)
+	}
+	if got, want := entryNames(config.Users), []string{"admin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("users are %v, want %v", got, want)
+	}
+	if config.CurrentContext != "" {
+		t.Errorf("current context is still the removed %q", config.CurrentContext)
+	}
+}
+
+func TestPruneThroughSymlink(t *testing.T) {
+	withFlag(t, &kubeconfigYes, true)
+	withFlag(t, &kubeconfigTimeout, time.Second)
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	target := writeKubeconfig(t, t.TempDir(), "config", `apiVersion: v1
+kind: Config
+clusters:
+- name: live
+  cluster:
+    server: https://`+server.Addr().String()+`
+contexts:
+- name: gone
+  context:
+    cluster: gone
+- name: live
+  context:
+    cluster: live
+`)
+	link := filepath.Join(t.TempDir(), "config")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := kubeconfigPruneCmd.RunE(kubeconfigPruneCmd, []string{link}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link is kept, and the file it points at loses the context of the undefined cluster
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%s is no longer a symlink: %v", link, err)
+	}
+	config, err := loadKubeconfig(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryNames(config.Contexts), []string{"live"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the link target has contexts %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// logsPollInterval is how often the pods are listed to pick up new pods and restarted containers
+const logsPollInterval = 2 * time.Second
+
+// logsColors are the ANSI colors the pod prefixes cycle through
+var logsColors = []int{31, 32, 33, 34, 35, 36, 91, 92, 93, 94, 95, 96}
+
+var (
+	// logsNamespace is the namespace to tail pods in, defaulting to the current context's
+	logsNamespace string
+	// logsSelector is a label selector the pods have to match
+	logsSelector string
+	// logsContainer is a regexp the container names have to match
+	logsContainer string
+	// logsTail is the number of existing lines shown for each container running when logs starts
+	logsTail int
+	// logsColor colors the prefixes: auto colors them when stdout is a terminal
+	logsColor string
+)
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().StringVarP(&logsNamespace, "namespace", "n", "", "Namespace of the pods (default the current context's)")
+	logsCmd.Flags().StringVarP(&logsSelector, "selector", "l", "", "Label selector the pods have to match, e.g. app=api")
+	logsCmd.Flags().StringVarP(&logsContainer, "container", "c", "", "Regexp the container names have to match")
+	logsCmd.Flags().IntVar(&logsTail, "tail", 10, "Lines of existing logs shown for each container running at start, -1 for all")
+	logsCmd.Flags().StringVar(&logsColor, "color", "auto", "Color the pod prefixes, one of: auto, always, never")
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [pod-regexp]",
+	Short: "Tails the logs of every container in the matching pods",
+	Long: "Follows the logs of every running container in the pods matching the regexp and --selector, interleaving" +
+		"\nthem with a colored pod/container prefix. New pods and restarted containers are picked up as they start," +
+		"\nfrom the beginning of their logs. Stops on Ctrl-C.",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		podPattern := ""
+		if len(args) == 1 {
+			podPattern = args[0]
+		}
+		pods, err := regexp.Compile(podPattern)
+		if err != nil {
+			return fmt.Errorf("invalid pod regexp: %w", err)
+		}
+		containers, err := regexp.Compile(logsContainer)
+		if err != nil {
+			return fmt.Errorf("invalid --container regexp: %w", err)
+		}
+		colored, err := useColor(logsColor)
+		if err != nil {
+			return err
+		}
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("logs needs kubectl on the PATH")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		tailer := &logTailer{pods: pods, containers: containers, colored: colored, tailing: make(map[string]struct{})}
+		return tailer.run(ctx)
+	},
+}
+
+// runningContainer is a container of a matching pod, identified by when it started so a restart is tailed again
+type runningContainer struct {
+	pod       string
+	container string
+	startedAt string
+}
+
+// key identifies this run of the container
+func (c runningContainer) key() string {
+	return c.pod + "/" + c.container + "@" + c.startedAt
+}
+
+// logTailer follows the logs of the matching containers, starting a kubectl logs for each one it finds
+type logTailer struct {
+	pods       *regexp.Regexp
+	containers *regexp.Regexp
+	colored    bool
+
+	output sync.Mutex
+	colors map[string]int
+	wg     sync.WaitGroup
+
+	// mu guards tailing, the containers being followed, and stopped, when each one's kubectl logs last exited
+	mu      sync.Mutex
+	tailing map[string]struct{}
+	stopped map[string]string
+}
+
+// run polls the pods until ctx is done, following each container the first time it's seen running
+func (t *logTailer) run(ctx context.Context) error {
+	first := true
+	for {
+		running, err := t.list()
+		if err != nil {
+			// The first listing failing means the flags are wrong, later ones are retried
+			if first {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		for _, container := range running {
+			if !t.start(container) {
+				continue
+			}
+			t.wg.Add(1)
+			go func(container runningContainer, first bool) {
+				defer t.wg.Done()
+				t.follow(ctx, container, first)
+			}(container, first)
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			t.wg.Wait()
+			return nil
+		case <-time.After(logsPollInterval):
+		}
+	}
+}
+
+// start marks the container as followed, reporting false if it already is
+func (t *logTailer) start(container runningContainer) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, done := t.tailing[container.key()]; done {
+		return false
+	}
+	t.tailing[container.key()] = struct{}{}
+	return true
+}
+
+// list returns the running containers of the pods matching the selector and regexps
+func (t *logTailer) list() ([]runningContainer, error) {
+	args := []string{"get", "pods",
+		`-o=jsonpath={range .items[*]}{.metadata.name}{"\t"}{range .status.containerStatuses[*]}{.name}{","}{.state.running.startedAt}{" "}{end}{"\n"}{end}`}
+	args = append(args, namespaceFlag(logsNamespace)...)
+	if logsSelector != "" {
+		args = append(args, "--selector="+logsSelector)
+	}
+	output, err := kubectlOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []runningContainer
+	for _, line := range strings.Split(output, "\n") {
+		pod, statuses, found := strings.Cut(line, "\t")
+		if !found || !t.pods.MatchString(pod) {
+			continue
+		}
+		for _, status := range strings.Fields(statuses) {
+			name, startedAt, _ := strings.Cut(status, ",")
+			if startedAt != "" && t.containers.MatchString(name) {
+				running = append(running, runningContainer{pod: pod, container: name, startedAt: startedAt})
+			}
+		}
+	}
+	return running, nil
+}
+
+// follow relays the container's logs until it stops or ctx is done. Containers running at start show the last
+// --tail lines, those found later everything since they started. When kubectl logs exits while the container is
+// still running, e.g. because the connection dropped, the container is released for the next poll to follow again
+// from where it stopped
+func (t *logTailer) follow(ctx context.Context, container runningContainer, existing bool) {
+	args := []string{"logs", "--follow", container.pod, "--container=" + container.container}
+	args = append(args, namespaceFlag(logsNamespace)...)
+	t.mu.Lock()
+	since, resumed := t.stopped[container.key()]
+	t.mu.Unlock()
+	switch {
+	case resumed:
+		args = append(args, "--since-time="+since)
+	case existing:
+		args = append(args, "--tail="+strconv.Itoa(logsTail))
+	default:
+		args = append(args, "--since-time="+container.startedAt)
+	}
+
+	prefix := t.prefix(container)
+	out := &lineWriter{onLine: func(line string) { t.print(prefix, line) }}
+	command := exec.CommandContext(ctx, "kubectl", args...)
+	command.Stdout, command.Stderr = out, out
+	command.WaitDelay = time.Second
+	command.Run()
+	out.flush()
+	if ctx.Err() != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped == nil {
+		t.stopped = make(map[string]string)
+	}
+	t.stopped[container.key()] = time.Now().UTC().Format(time.RFC3339)
+	delete(t.tailing, container.key())
+}
+
+// prefix returns the pod/container prefix of the container's lines, colored by pod when enabled
+func (t *logTailer) prefix(container runningContainer) string {
+	prefix := container.pod + "/" + container.container
+	if !t.colored {
+		return prefix
+	}
+
+	t.output.Lock()
+	defer t.output.Unlock()
+	if t.colors == nil {
+		t.colors = make(map[string]int)
+	}
+	color, ok := t.colors[container.pod]
+	if !ok {
+		color = logsColors[len(t.colors)%len(logsColors)]
+		t.colors[container.pod] = color
+	}
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, prefix)
+}
+
+// print writes a line under the prefix, keeping lines from different containers whole
+func (t *logTailer) print(prefix string, line string) {
+	t.output.Lock()
+	defer t.output.Unlock()
+	fmt.Printf("%s %s\n", prefix, line)
+}
+
+// useColor resolves the --color mode, where auto colors output written to a terminal
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("unknown color mode %q, expected auto, always or never", mode)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLogTailerList(t *testing.T) {
+	// api-2 is still pulling its image, and the sidecar of api-1 is waiting, so neither has a start time
+	fakeKubectl(t, "printf 'api-1\\tapp,2024-01-01T00:00:00Z sidecar, \\n'\n"+
+		"printf 'api-2\\tapp, \\n'\n"+
+		"printf 'worker-1\\tapp,2024-01-01T00:00:00Z \\n'\n"+
+		"printf 'api-3\\tapp,2024-01-02T00:00:00Z proxy,2024-01-02T00:00:00Z \\n'\n")
+	tests := []struct {
+		pods       string
+		containers string
+		want       []runningContainer
+	}{
+		{"", "", []runningContainer{
+			{"api-1", "app", "2024-01-01T00:00:00Z"},
+			{"worker-1", "app", "2024-01-01T00:00:00Z"},
+			{"api-3", "app", "2024-01-02T00:00:00Z"},
+			{"api-3", "proxy", "2024-01-02T00:00:00Z"},
+		}},
+		{"^api", "", []runningContainer{
+			{"api-1", "app", "2024-01-01T00:00:00Z"},
+			{"api-3", "app", "2024-01-02T00:00:00Z"},
+			{"api-3", "proxy", "2024-01-02T00:00:00Z"},
+		}},
+		{"", "^proxy$", []runningContainer{{"api-3", "proxy", "2024-01-02T00:00:00Z"}}},
+		{"^db", "", nil},
+	}
+	for _, test := range tests {
+		tailer := &logTailer{pods: regexp.MustCompile(test.pods), containers: regexp.MustCompile(test.containers)}
+		got, err := tailer.list()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("pods %q, containers %q: got %v, want %v", test.pods, test.containers, got, test.want)
+		}
+	}
+}
+
+func TestLogTailerPrefix(t *testing.T) {
+	plain := &logTailer{}
+	if got := plain.prefix(runningContainer{pod: "api-1", container: "app"}); got != "api-1/app" {
+		t.Errorf("got %q without color, want api-1/app", got)
+	}
+
+	// Containers of the same pod share its color, and each new pod takes the next one
+	colored := &logTailer{colored: true}
+	tests := []struct {
+		container runningContainer
+		want      string
+	}{
+		{runningContainer{pod: "api-1", container: "app"}, "\x1b[31mapi-1/app\x1b[0m"},
+		{runningContainer{pod: "api-2", container: "app"}, "\x1b[32mapi-2/app\x1b[0m"},
+		{runningContainer{pod: "api-1", container: "proxy"}, "\x1b[31mapi-1/proxy\x1b[0m"},
+	}
+	for _, test := range tests {
+		if got := colored.prefix(test.container); got != test.want {
+			t.Errorf("%s/%s: got %q, want %q", test.container.pod, test.container.container, got, test.want)
+		}
+	}
+}
+
+func TestLogTailerFollowReleasesStoppedContainer(t *testing.T) {
+	// The fake kubectl prints a line and exits, as kubectl logs does when its connection drops
+	dir := fakeKubectl(t, "printf '%s\\n' \"$@\" >> \"$KT_TEST_DIR/args\"\nprintf 'ready\\n'\n")
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFlag(t, &os.Stdout, writer)
+
+	container := runningContainer{pod: "api-1", container: "app", startedAt: "2024-01-01T00:00:00Z"}
+	tailer := &logTailer{tailing: make(map[string]struct{})}
+	if !tailer.start(container) || tailer.start(container) {
+		t.Fatal("a container already followed was started again")
+	}
+	tailer.follow(context.Background(), container, true)
+	// Once released, the next poll follows it again from when the first kubectl logs exited
+	if !tailer.start(container) {
+		t.Fatal("the container is still marked as followed after kubectl logs exited")
+	}
+	tailer.follow(context.Background(), container, false)
+	writer.Close()
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(out), "api-1/app ready\napi-1/app ready\n"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := strings.SplitAfter(string(args), "--tail=10\n")
+	if len(runs) != 2 || !strings.HasPrefix(runs[1], "logs\n--follow\napi-1\n--container=app\n--since-time=") ||
+		strings.Contains(runs[1], container.startedAt) {
+		t.Errorf("got kubectl runs %q, want the first to show the tail and the second to resume where it stopped", args)
+	}
+}
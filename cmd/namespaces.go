@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// formatExtensions maps each format other than shell to the extension of the files it's written to
+var formatExtensions = map[string]string{
+	"fzf":         ".tsv",
+	"assoc-array": ".sh",
+	"tmux":        ".tmux.conf",
+	"zsh-abbr":    ".zsh",
+	"fish-abbr":   ".fish",
+	"toml":        ".toml",
+	"json":        ".json",
+	"markdown":    ".md",
+}
+
+// shellExtensions maps each shell to the extension of the files the shell format is written to for it
+var shellExtensions = map[string]string{
+	"bash":       ".sh",
+	"zsh":        ".sh",
+	"fish":       ".fish",
+	"powershell": ".ps1",
+}
+
+// aliasFileExtension returns the extension of the files the format is written to for the shell
+func aliasFileExtension(format, shell string) string {
+	if extension, ok := formatExtensions[format]; ok {
+		return extension
+	}
+	return shellExtensions[shell]
+}
+
+// writeNamespaceFiles writes one alias file per namespace into dir, each with the namespace baked into the base
+// commands, the tools' included, in place of the parts that pick a namespace
+func writeNamespaceFiles(namespaces []string, dir string) error {
+	for _, namespace := range namespaces {
+		if !aliases.NamespacePattern.MatchString(namespace) {
+			return fmt.Errorf("invalid namespace %q", namespace)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	extension := aliasFileExtension(aliasFormat, aliasShell)
+	mode, err := outputFileMode()
+	if err != nil {
+		return err
+	}
+	for _, namespace := range namespaces {
+		ag, err := newAliasGenerator()
+		if err != nil {
+			return err
+		}
+		ag.ScopeToNamespace(namespace)
+
+		out, err := render(&ag)
+		if err != nil {
+			return err
+		}
+		out = addGeneratedHeader(out, time.Now())
+		if err := writeFileAtomicMode(filepath.Join(dir, namespace+extension), out, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteNamespaceFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tests := []struct {
+		format    string
+		shell     string
+		extension string
+		header    bool
+	}{
+		{"shell", "bash", ".sh", true},
+		{"shell", "fish", ".fish", true},
+		{"shell", "powershell", ".ps1", true},
+		{"assoc-array", "zsh", ".sh", true},
+		{"zsh-abbr", "zsh", ".zsh", true},
+		{"fish-abbr", "fish", ".fish", true},
+		{"tmux", "bash", ".tmux.conf", true},
+		{"toml", "bash", ".toml", true},
+		{"fzf", "bash", ".tsv", false},
+		{"json", "bash", ".json", false},
+		{"markdown", "bash", ".md", false},
+	}
+	for _, test := range tests {
+		t.Run(test.format+"/"+test.shell, func(t *testing.T) {
+			withFlag(t, &aliasFormat, test.format)
+			withFlag(t, &aliasShell, test.shell)
+			dir := t.TempDir()
+			if err := writeNamespaceFiles([]string{"payments", "web"}, dir); err != nil {
+				t.Fatal(err)
+			}
+			for _, namespace := range []string{"payments", "web"} {
+				out, err := os.ReadFile(filepath.Join(dir, namespace+test.extension))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := strings.HasPrefix(string(out), generatedHeaderPrefix); got != test.header {
+					t.Errorf("%s%s starts with the generated header: %v, want %v", namespace, test.extension, got, test.header)
+				}
+				if !strings.Contains(string(out), "kubectl --namespace="+namespace+" get pods") {
+					t.Errorf("%s%s doesn't bake in the namespace", namespace, test.extension)
+				}
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+				t.Errorf("wrote %d files, want one per namespace", len(entries))
+			}
+		})
+	}
+
+	if err := writeNamespaceFiles([]string{"Payments"}, t.TempDir()); err == nil {
+		t.Errorf("an invalid namespace was accepted")
+	}
+}
+
+func TestNamespaceFilesOnlyUseTheirNamespace(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	withFlag(t, &aliasFormat, "shell")
+	withFlag(t, &aliasShell, "bash")
+	withFlag(t, &aliasTools, []string{"kubectl", "helm"})
+	dir := t.TempDir()
+	if err := writeNamespaceFiles([]string{"team-a"}, dir); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "team-a.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	definitions := parseDefinitions(string(out))
+
+	// Nothing picks another namespace than the baked-in one, whether all of them, one to type in or a shortcut
+	for name, definition := range definitions {
+		if strings.Contains(definition, "--all-namespaces") || strings.HasSuffix(strings.TrimSuffix(definition, "'"), "--namespace") {
+			t.Errorf("%s picks another namespace: %s", name, definition)
+		}
+	}
+	for _, name := range []string{"kgpoall", "kgpon", "ksysgpo", "hlsall", "hlsn"} {
+		if definition, ok := definitions[name]; ok {
+			t.Errorf("unexpected %s", definition)
+		}
+	}
+	want := map[string]string{
+		"kgpo": "alias kgpo='kubectl --namespace=team-a get pods'",
+		"hls":  "alias hls='helm --namespace=team-a list'",
+	}
+	for name, definition := range want {
+		if definitions[name] != definition {
+			t.Errorf("got %q, want %q", definitions[name], definition)
+		}
+	}
+}
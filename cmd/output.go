@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// generatedHeaderPrefix starts the comment line written at the top of alias files
+const generatedHeaderPrefix = "# Generated by kt "
+
+// generatedHeader returns the comment line recording the tool version and when the file was generated
+func generatedHeader(now time.Time) string {
+	return fmt.Sprintf("%s%s at %s\n", generatedHeaderPrefix, version, now.UTC().Format(time.RFC3339))
+}
+
+// addGeneratedHeader starts the output with the generated header. The picker reads every line as an alias and json
+// and markdown have no comments, so only the other formats get it
+func addGeneratedHeader(out []byte, now time.Time) []byte {
+	if !hasComments(aliasFormat) {
+		return out
+	}
+	return append([]byte(generatedHeader(now)), out...)
+}
+
+// stripGeneratedHeader removes the generated header line from the start of an alias file, if present
+func stripGeneratedHeader(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte(generatedHeaderPrefix)) {
+		return data
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[i+1:]
+	}
+	return nil
+}
+
+// outputFileMode returns the --output-perm permissions that alias files are written with
+func outputFileMode() (os.FileMode, error) {
+	perm, err := strconv.ParseUint(outputPerm, 8, 32)
+	if err != nil || perm > 0o777 {
+		return 0, fmt.Errorf("invalid --output-perm %q, expected octal permissions such as 0644", outputPerm)
+	}
+	return os.FileMode(perm), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place, so a failure
+// partway through never leaves a half-written file behind
+func writeFileAtomic(path string, data []byte) error {
+	return writeFileAtomicMode(path, data, 0o644)
+}
+
+// writeFileAtomicMode is writeFileAtomic leaving the file with the given permissions, for files holding credentials
+func writeFileAtomicMode(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Delays between restarts of a failing port-forward, doubling from the first up to the last
+const (
+	pfMinBackoff = time.Second
+	pfMaxBackoff = 30 * time.Second
+)
+
+// pfLostConnection holds kubectl messages meaning the forward's pod has gone, after which kubectl keeps running
+// but every connection fails, so the forward is restarted against the new pod
+var pfLostConnection = []string{"lost connection to pod", "an error occurred forwarding"}
+
+// pfFile is the profiles file, defaulting to ~/.kube-tools/port-forwards.yaml
+var pfFile string
+
+func init() {
+	rootCmd.AddCommand(pfCmd)
+	pfCmd.AddCommand(pfStartCmd)
+	pfCmd.AddCommand(pfListCmd)
+	pfCmd.PersistentFlags().StringVar(&pfFile, "file", "", "YAML file defining the port-forward profiles (default ~/.kube-tools/port-forwards.yaml)")
+}
+
+var pfCmd = &cobra.Command{
+	Use:   "pf",
+	Short: "Runs the port-forwards defined in a profiles file",
+	Long: "Manages long-lived kubectl port-forwards grouped into named profiles in a YAML file, mapping each profile" +
+		"\nto its forwards:" +
+		"\n\n  dev:\n    - target: svc/api\n      namespace: backend\n      local: 8080\n      remote: 80",
+}
+
+var pfStartCmd = &cobra.Command{
+	Use:   "start [profile...]",
+	Short: "Runs the forwards of the given profiles, or of every profile, until interrupted",
+	Long: "Runs a kubectl port-forward for each forward of the given profiles, or of every profile, concurrently." +
+		"\nA forward that exits or loses its pod, e.g. because the pod restarted, is started again, waiting up to" +
+		"\n30 seconds between attempts while it keeps failing. Stops every forward on Ctrl-C.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := loadPortForwards()
+		if err != nil {
+			return err
+		}
+		forwards, err := selectPortForwards(profiles, args)
+		if err != nil {
+			return err
+		}
+		if err := checkLocalPorts(forwards); err != nil {
+			return err
+		}
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("pf needs kubectl on the PATH")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		var wg sync.WaitGroup
+		for _, forward := range forwards {
+			wg.Add(1)
+			go func(forward PortForward) {
+				defer wg.Done()
+				keepForwarding(ctx, forward)
+			}(forward)
+		}
+		wg.Wait()
+		return nil
+	},
+}
+
+var pfListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Lists the forwards of every profile and whether their local port is listening",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := loadPortForwards()
+		if err != nil {
+			return err
+		}
+		forwards, err := selectPortForwards(profiles, nil)
+		if err != nil {
+			return err
+		}
+
+		out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(out, "PROFILE\tTARGET\tNAMESPACE\tPORTS\tSTATUS")
+		for _, forward := range forwards {
+			status := "down"
+			if isListening(forward.Local) {
+				status = "listening"
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\t%d:%d\t%s\n", forward.Profile, forward.Target, forward.Namespace, forward.Local, forward.Remote, status)
+		}
+		return out.Flush()
+	},
+}
+
+// PortForward is a single forward of a profile
+type PortForward struct {
+	// Target is the pod or service to forward to, e.g. svc/api or pod/api-0
+	Target string `yaml:"target"`
+	// Namespace and Context default to the current context's when empty
+	Namespace string `yaml:"namespace,omitempty"`
+	Context   string `yaml:"context,omitempty"`
+	// Local is the port on localhost forwarded to the Remote port of the target
+	Local  int `yaml:"local"`
+	Remote int `yaml:"remote"`
+	// Profile is the name of the profile the forward belongs to
+	Profile string `yaml:"-"`
+}
+
+// name identifies the forward in pf's output
+func (f PortForward) name() string {
+	return fmt.Sprintf("%s/%s:%d", f.Profile, f.Target, f.Local)
+}
+
+// args returns the kubectl arguments that run the forward
+func (f PortForward) args() []string {
+	args := []string{"port-forward"}
+	if f.Context != "" {
+		args = append(args, "--context="+f.Context)
+	}
+	if f.Namespace != "" {
+		args = append(args, "--namespace="+f.Namespace)
+	}
+	return append(args, f.Target, fmt.Sprintf("%d:%d", f.Local, f.Remote))
+}
+
+// pfFilePath returns the --file path, or else the default profiles file
+func pfFilePath() (string, error) {
+	if pfFile != "" {
+		return pfFile, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube-tools", "port-forwards.yaml"), nil
+}
+
+// loadPortForwards reads the profiles file, rejecting unknown fields and forwards without a target or valid ports
+func loadPortForwards() (map[string][]PortForward, error) {
+	path, err := pfFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string][]PortForward
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&profiles); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing profiles %s: %w", path, err)
+	}
+	for profile, forwards := range profiles {
+		for i, forward := range forwards {
+			if forward.Target == "" {
+				return nil, fmt.Errorf("profiles %s: forward %d of %s has no target", path, i+1, profile)
+			}
+			if !isPort(forward.Local) || !isPort(forward.Remote) {
+				return nil, fmt.Errorf("profiles %s: %s in %s needs local and remote ports from 1 to 65535", path, forward.Target, profile)
+			}
+			forwards[i].Profile = profile
+		}
+	}
+	return profiles, nil
+}
+
+// selectPortForwards returns the forwards of the named profiles, or of every profile when none are named, ordered
+// by profile
+func selectPortForwards(profiles map[string][]PortForward, names []string) ([]PortForward, error) {
+	if len(names) == 0 {
+		for name := range profiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+
+	var selected []PortForward
+	for _, name := range names {
+		forwards, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("no profile %q", name)
+		}
+		selected = append(selected, forwards...)
+	}
+	return selected, nil
+}
+
+// checkLocalPorts fails when two of the forwards would listen on the same local port
+func checkLocalPorts(forwards []PortForward) error {
+	used := make(map[int]string)
+	for _, forward := range forwards {
+		if other, taken := used[forward.Local]; taken {
+			return fmt.Errorf("%s and %s both forward local port %d", other, forward.name(), forward.Local)
+		}
+		used[forward.Local] = forward.name()
+	}
+	return nil
+}
+
+// keepForwarding runs the forward until ctx is done, restarting it whenever it stops and backing off while it
+// keeps failing. A forward that stayed up longer than the longest delay starts over from the shortest
+func keepForwarding(ctx context.Context, forward PortForward) {
+	backoff := pfMinBackoff
+	for {
+		started := time.Now()
+		err := forwardOnce(ctx, forward)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > pfMaxBackoff {
+			backoff = pfMinBackoff
+		}
+		reason := "exited"
+		if err != nil {
+			reason = err.Error()
+		}
+		pfLog(forward, "stopped (%s), reconnecting in %s", reason, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, pfMaxBackoff)
+	}
+}
+
+// forwardOnce runs kubectl port-forward, relaying its output, until it exits, loses its pod or ctx is done
+func forwardOnce(ctx context.Context, forward PortForward) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lost := false
+	stdout := &lineWriter{onLine: func(line string) { pfLog(forward, "%s", line) }}
+	stderr := &lineWriter{onLine: func(line string) {
+		pfLog(forward, "%s", line)
+		for _, message := range pfLostConnection {
+			if strings.Contains(line, message) {
+				lost = true
+				cancel()
+			}
+		}
+	}}
+
+	command := exec.CommandContext(ctx, "kubectl", forward.args()...)
+	command.Stdout, command.Stderr = stdout, stderr
+	// Don't wait on output still held open by anything kubectl started once it's been stopped
+	command.WaitDelay = time.Second
+	err := command.Run()
+	stdout.flush()
+	stderr.flush()
+	if lost {
+		return fmt.Errorf("lost connection to the pod")
+	}
+	return err
+}
+
+// lineWriter passes each complete line written to it to onLine
+type lineWriter struct {
+	pending []byte
+	onLine  func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+		w.onLine(string(w.pending[:end]))
+		w.pending = w.pending[end+1:]
+	}
+}
+
+// flush passes on the last line if it wasn't terminated
+func (w *lineWriter) flush() {
+	if len(w.pending) > 0 {
+		w.onLine(string(w.pending))
+		w.pending = nil
+	}
+}
+
+// pfOutput serializes the output of the concurrent forwards so their lines don't interleave
+var pfOutput sync.Mutex
+
+// pfLog prints a line prefixed with the forward's name
+func pfLog(forward PortForward, format string, args ...any) {
+	pfOutput.Lock()
+	defer pfOutput.Unlock()
+	fmt.Printf("[%s] %s\n", forward.name(), fmt.Sprintf(format, args...))
+}
+
+// isPort reports whether port is a valid TCP port number
+func isPort(port int) bool {
+	return port >= 1 && port <= 65535
+}
+
+// isListening reports whether something accepts connections on the local port
+func isListening(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)), 200*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
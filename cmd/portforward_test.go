@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPortForwards(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]PortForward
+		err     string
+	}{
+		{
+			name: "profiles",
+			content: "dev:\n  - target: svc/api\n    namespace: backend\n    local: 8080\n    remote: 80\n" +
+				"db:\n  - target: pod/postgres-0\n    context: staging\n    local: 5432\n    remote: 5432\n",
+			want: map[string][]PortForward{
+				"dev": {{Target: "svc/api", Namespace: "backend", Local: 8080, Remote: 80, Profile: "dev"}},
+				"db":  {{Target: "pod/postgres-0", Context: "staging", Local: 5432, Remote: 5432, Profile: "db"}},
+			},
+		},
+		{name: "empty", content: ""},
+		{name: "unknown field", content: "dev:\n  - target: svc/api\n    locl: 8080\n    remote: 80\n", err: "field locl not found"},
+		{name: "no target", content: "dev:\n  - local: 8080\n    remote: 80\n", err: "forward 1 of dev has no target"},
+		{name: "bad port", content: "dev:\n  - target: svc/api\n    local: 70000\n    remote: 80\n", err: "svc/api in dev needs local and remote ports"},
+		{name: "no remote", content: "dev:\n  - target: svc/api\n    local: 8080\n", err: "svc/api in dev needs local and remote ports"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "port-forwards.yaml")
+		if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		withFlag(t, &pfFile, path)
+		got, err := loadPortForwards()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSelectPortForwards(t *testing.T) {
+	profiles := map[string][]PortForward{
+		"dev": {{Target: "svc/api", Local: 8080, Profile: "dev"}, {Target: "svc/web", Local: 3000, Profile: "dev"}},
+		"db":  {{Target: "pod/postgres-0", Local: 5432, Profile: "db"}},
+	}
+	tests := []struct {
+		names []string
+		want  []string
+		err   string
+	}{
+		// Every profile is selected by default, in name order
+		{nil, []string{"db/pod/postgres-0:5432", "dev/svc/api:8080", "dev/svc/web:3000"}, ""},
+		{[]string{"dev"}, []string{"dev/svc/api:8080", "dev/svc/web:3000"}, ""},
+		{[]string{"dev", "db"}, []string{"dev/svc/api:8080", "dev/svc/web:3000", "db/pod/postgres-0:5432"}, ""},
+		{[]string{"prod"}, nil, `no profile "prod"`},
+	}
+	for _, test := range tests {
+		got, err := selectPortForwards(profiles, test.names)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%v: got error %v, want %q", test.names, err, test.err)
+			}
+			continue
+		}
+		var names []string
+		for _, forward := range got {
+			names = append(names, forward.name())
+		}
+		if err != nil || !reflect.DeepEqual(names, test.want) {
+			t.Errorf("%v: got %v, %v, want %v", test.names, names, err, test.want)
+		}
+	}
+}
+
+func TestCheckLocalPorts(t *testing.T) {
+	api := PortForward{Target: "svc/api", Local: 8080, Profile: "dev"}
+	web := PortForward{Target: "svc/web", Local: 3000, Profile: "dev"}
+	other := PortForward{Target: "svc/api", Local: 8080, Profile: "staging"}
+	if err := checkLocalPorts([]PortForward{api, web}); err != nil {
+		t.Errorf("got %v for distinct local ports", err)
+	}
+	want := "dev/svc/api:8080 and staging/svc/api:8080 both forward local port 8080"
+	if err := checkLocalPorts([]PortForward{api, web, other}); err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
+
+func TestPortForwardArgs(t *testing.T) {
+	tests := []struct {
+		forward PortForward
+		want    []string
+	}{
+		{PortForward{Target: "svc/api", Local: 8080, Remote: 80}, []string{"port-forward", "svc/api", "8080:80"}},
+		{
+			PortForward{Target: "pod/postgres-0", Namespace: "db", Context: "staging", Local: 5432, Remote: 5432},
+			[]string{"port-forward", "--context=staging", "--namespace=db", "pod/postgres-0", "5432:5432"},
+		},
+	}
+	for _, test := range tests {
+		if got := test.forward.args(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %v, want %v", got, test.want)
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var got []string
+	w := &lineWriter{onLine: func(line string) { got = append(got, line) }}
+	for _, chunk := range []string{"Forwarding from 127.0.0.1:8080", " -> 80\nHandling conn", "ection for 8080\n\nlast"} {
+		w.Write([]byte(chunk))
+	}
+	w.flush()
+	want := []string{"Forwarding from 127.0.0.1:8080 -> 80", "Handling connection for 8080", "", "last"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestForwardOnceLostConnection(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	withFlag(t, &os.Stdout, devNull)
+	// kubectl keeps running once the pod is gone, so the forward has to be stopped on the message alone
+	fakeKubectl(t, "echo 'E0101 portforward.go:413] lost connection to pod' >&2\nexec sleep 30\n")
+
+	forward := PortForward{Target: "svc/api", Local: 8080, Remote: 80, Profile: "dev"}
+	started := time.Now()
+	err = forwardOnce(context.Background(), forward)
+	if err == nil || err.Error() != "lost connection to the pod" {
+		t.Errorf("got %v, want the lost connection", err)
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("the forward took %s to stop after losing its pod", elapsed)
+	}
+
+	fakeKubectl(t, "echo 'error: services \"api\" not found' >&2\nexit 1\n")
+	if err := forwardOnce(context.Background(), forward); err == nil || err.Error() == "lost connection to the pod" {
+		t.Errorf("got %v, want kubectl's exit status", err)
+	}
+}
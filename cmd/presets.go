@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	aliasesCmd.AddCommand(profilesCmd)
+}
+
+var profilesCmd = &cobra.Command{
+	Use:          "profiles",
+	Short:        "Lists the available presets",
+	Long:         "Lists every preset that can be selected with --preset, built-in or from the config file, along with where\nit's defined and the flags it sets.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		available, err := availablePresets()
+		if err != nil {
+			return err
+		}
+		writeProfiles(os.Stdout, available)
+		return nil
+	},
+}
+
+// writeProfiles lists the presets by name with where each is defined, its description and its flags
+func writeProfiles(out io.Writer, available map[string]Preset) {
+	for _, name := range presetNames(available) {
+		preset := available[name]
+		fmt.Fprintf(out, "%s (%s)\n  %s\n  %s\n", name, preset.source, preset.Description, strings.Join(preset.flagArgs(), " "))
+	}
+}
+
+// Preset bundles several aliases flags under a single name
+type Preset struct {
+	aliases.Preset
+
+	// source is where the preset is defined, built-in or the config file's path
+	source string
+}
+
+// presets holds the built-in presets, selected with --preset
+var presets = map[string]Preset{
+	"dotfile": {
+		Preset: aliases.Preset{
+			Description: "Shell aliases that are safe to source from shared dotfiles",
+			Flags:       map[string]string{"format": "shell", "guard-binary": "true", "check-syntax": "true"},
+		},
+	},
+	"picker": {
+		Preset: aliases.Preset{
+			Description: "Tab-separated output for fuzzy-finding aliases with fzf",
+			Flags:       map[string]string{"format": "fzf"},
+		},
+	},
+	"sre": {
+		Preset: aliases.Preset{
+			Description: "Advanced, VPA and deprecated resources, for debugging clusters",
+			Flags:       map[string]string{"advanced": "true", "vpa": "true", "include-deprecated": "true"},
+		},
+	},
+}
+
+// flagArgs returns the preset's flags as command line arguments, sorted by flag name
+func (p Preset) flagArgs() []string {
+	var args []string
+	for flag, value := range p.Flags {
+		args = append(args, fmt.Sprintf("--%s=%s", flag, value))
+	}
+	sort.Strings(args)
+	return args
+}
+
+// availablePresets returns the built-in presets with the config file's merged over them, a config preset
+// replacing the built-in one of the same name
+func availablePresets() (map[string]Preset, error) {
+	available := make(map[string]Preset, len(presets))
+	for name, preset := range presets {
+		preset.source = "built-in"
+		available[name] = preset
+	}
+	path := configFile()
+	if path == "" {
+		return available, nil
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	for name, preset := range config.Presets {
+		available[name] = Preset{Preset: preset, source: path}
+	}
+	return available, nil
+}
+
+// applyPreset sets the flags bundled in the named preset. Flags given explicitly on the command line win
+func applyPreset(cmd *cobra.Command, available map[string]Preset, name string) error {
+	preset, ok := available[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q, expected one of: %s", name, strings.Join(presetNames(available), ", "))
+	}
+	for flag, value := range preset.Flags {
+		if flag == "preset" || flag == "config" {
+			return fmt.Errorf("preset %s: can't set --%s", name, flag)
+		}
+		if cmd.Flags().Changed(flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(flag, value); err != nil {
+			return fmt.Errorf("preset %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// presetNames returns the names of the presets in alphabetical order
+func presetNames(presets map[string]Preset) []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
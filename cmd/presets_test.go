@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withConfig points --config at a file holding content for the duration of the test
+func withConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "aliases.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	previous := configPath
+	configPath = path
+	t.Cleanup(func() { configPath = previous })
+	return path
+}
+
+// presetCommand returns a command with the flags the built-in presets set
+func presetCommand() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("format", "shell", "")
+	cmd.Flags().String("shell", "bash", "")
+	cmd.Flags().Bool("guard-binary", false, "")
+	cmd.Flags().Bool("check-syntax", false, "")
+	cmd.Flags().Bool("advanced", false, "")
+	return cmd
+}
+
+func TestApplyPreset(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		explicit map[string]string
+		want     map[string]string
+	}{
+		{
+			name:   "sets every flag",
+			preset: "dotfile",
+			want:   map[string]string{"format": "shell", "guard-binary": "true", "check-syntax": "true"},
+		},
+		{
+			name:     "explicit flags win",
+			preset:   "dotfile",
+			explicit: map[string]string{"format": "assoc-array", "check-syntax": "false"},
+			want:     map[string]string{"format": "assoc-array", "guard-binary": "true", "check-syntax": "false"},
+		},
+		{
+			name:   "leaves other flags alone",
+			preset: "picker",
+			want:   map[string]string{"format": "fzf", "guard-binary": "false", "shell": "bash"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := presetCommand()
+			for flag, value := range test.explicit {
+				if err := cmd.Flags().Set(flag, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := applyPreset(cmd, presets, test.preset); err != nil {
+				t.Fatal(err)
+			}
+			for flag, want := range test.want {
+				if got := cmd.Flags().Lookup(flag).Value.String(); got != want {
+					t.Errorf("--%s is %q, want %q", flag, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPresetErrors(t *testing.T) {
+	available := map[string]Preset{
+		"typo":      {Preset: aliases.Preset{Flags: map[string]string{"fromat": "fzf"}}},
+		"recursive": {Preset: aliases.Preset{Flags: map[string]string{"preset": "typo"}}},
+	}
+	for _, name := range []string{"missing", "typo", "recursive"} {
+		if err := applyPreset(presetCommand(), available, name); err == nil {
+			t.Errorf("preset %s applied without an error", name)
+		}
+	}
+}
+
+func TestAvailablePresetsFromConfig(t *testing.T) {
+	withConfig(t, `presets:
+  sre-zsh:
+    description: SRE resources as zsh abbreviations
+    flags:
+      advanced: true
+      format: zsh-abbr
+      shell: zsh
+  picker:
+    flags:
+      format: fzf
+      guard-binary: true
+`)
+	available, err := availablePresets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := available["dotfile"]; !ok {
+		t.Error("built-in dotfile preset is missing")
+	}
+	if got := available["picker"].Flags["guard-binary"]; got != "true" {
+		t.Errorf("config picker preset doesn't replace the built-in one, guard-binary is %q", got)
+	}
+
+	cmd := presetCommand()
+	if err := applyPreset(cmd, available, "sre-zsh"); err != nil {
+		t.Fatal(err)
+	}
+	for flag, want := range map[string]string{"advanced": "true", "format": "zsh-abbr", "shell": "zsh"} {
+		if got := cmd.Flags().Lookup(flag).Value.String(); got != want {
+			t.Errorf("--%s is %q, want %q", flag, got, want)
+		}
+	}
+}
+
+func TestWriteProfiles(t *testing.T) {
+	path := withConfig(t, "presets:\n  team:\n    description: The team's resources\n    flags:\n      cert-manager: true\n")
+	available, err := availablePresets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	writeProfiles(&out, available)
+
+	for _, name := range presetNames(presets) {
+		if !strings.Contains(out.String(), name+" (built-in)\n") {
+			t.Errorf("built-in preset %s isn't listed:\n%s", name, out.String())
+		}
+	}
+	if want := "team (" + path + ")\n  The team's resources\n  --cert-manager=true\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("config preset isn't listed as %q:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// promptShell selects the shell the prompt function is generated for
+var promptShell string
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+	promptCmd.Flags().StringVar(&promptShell, "shell", "bash", "Shell to generate the prompt function for, one of: bash, zsh, fish")
+}
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Generates a prompt segment showing the active kube context",
+	Long: "Generates a shell function, kube_prompt, that prints the current kubectl context and namespace" +
+		"\nfor use in PS1, PROMPT/RPROMPT or fish_prompt.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		function, ok := promptFunction(promptShell)
+		if !ok {
+			return fmt.Errorf("unknown shell %q, expected bash, zsh or fish", promptShell)
+		}
+		fmt.Print(function)
+		return nil
+	},
+}
+
+// promptUsage holds, for each supported shell, how to add kube_prompt to the prompt
+var promptUsage = map[string][]string{
+	"bash": {`Add to PS1, e.g. PS1='$(kube_prompt) \$ '`},
+	"zsh":  {"Add to PROMPT or RPROMPT with prompt substitution, e.g.", "  setopt PROMPT_SUBST", "  RPROMPT='$(kube_prompt)'"},
+	"fish": {"Call from fish_prompt or fish_right_prompt, e.g. function fish_right_prompt; kube_prompt; end"},
+}
+
+// promptFunctions holds the kube_prompt function for each supported shell
+var promptFunctions = map[string]string{
+	"bash": `kube_prompt() {
+  local ctx ns
+  ctx=$(kubectl config current-context 2>/dev/null) || return
+  ns=$(kubectl config view --minify --output 'jsonpath={..namespace}' 2>/dev/null)
+  printf '%s/%s' "$ctx" "${ns:-default}"
+}
+`,
+	"zsh": `kube_prompt() {
+  local ctx ns
+  ctx=$(kubectl config current-context 2>/dev/null) || return
+  ns=$(kubectl config view --minify --output 'jsonpath={..namespace}' 2>/dev/null)
+  printf '%s/%s' "$ctx" "${ns:-default}"
+}
+`,
+	"fish": `function kube_prompt
+    set -l ctx (kubectl config current-context 2>/dev/null); or return
+    set -l ns (kubectl config view --minify --output 'jsonpath={..namespace}' 2>/dev/null)
+    test -n "$ns"; or set ns default
+    printf '%s/%s' $ctx $ns
+end
+`,
+}
+
+// promptFunction returns the shell's kube_prompt function, headed by comments on adding it to the prompt
+func promptFunction(shell string) (string, bool) {
+	function, ok := promptFunctions[shell]
+	if !ok {
+		return "", false
+	}
+	var usage strings.Builder
+	for _, line := range promptUsage[shell] {
+		usage.WriteString(comment(line) + "\n")
+	}
+	return usage.String() + function, true
+}
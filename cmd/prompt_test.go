@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPromptFunction(t *testing.T) {
+	tests := []struct {
+		shell      string
+		definition string
+		end        string
+	}{
+		{"bash", "kube_prompt() {", "}"},
+		{"zsh", "kube_prompt() {", "}"},
+		{"fish", "function kube_prompt", "end"},
+	}
+	for _, test := range tests {
+		function, ok := promptFunction(test.shell)
+		if !ok {
+			t.Fatalf("no prompt function for %s", test.shell)
+		}
+		got := lines(function)
+		// The usage comes first, as comments, then a single function
+		start := 0
+		for start < len(got) && strings.HasPrefix(got[start], comment("")) {
+			start++
+		}
+		if start == 0 || start == len(got) || got[start] != test.definition || got[len(got)-1] != test.end {
+			t.Errorf("%s: the prompt isn't a commented function:\n%s", test.shell, function)
+		}
+	}
+	if _, ok := promptFunction("powershell"); ok {
+		t.Errorf("got a prompt function for powershell")
+	}
+}
+
+func TestPromptFunctionPrintsContext(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		function, _ := promptFunction(shell)
+		tests := map[string]string{"web": "dev/web", "": "dev/default"}
+		for namespace, want := range tests {
+			fakeKubectl(t, "case \"$2\" in current-context) echo dev;; view) printf '"+namespace+"';; esac\n")
+			output, err := exec.Command(shell, "-c", function+"kube_prompt").CombinedOutput()
+			if err != nil || string(output) != want {
+				t.Errorf("%s with namespace %q: kube_prompt printed %q, %v, want %s", shell, namespace, output, err, want)
+			}
+		}
+	}
+}
+
+func TestPromptFunctionWithoutContext(t *testing.T) {
+	// Without a current context kubectl fails, and the prompt shows nothing rather than an empty context
+	fakeKubectl(t, "echo 'error: current-context is not set' >&2\nexit 1\n")
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		function, _ := promptFunction(shell)
+		output, err := exec.Command(shell, "-c", function+"kube_prompt").CombinedOutput()
+		if len(output) > 0 || err == nil {
+			t.Errorf("%s: kube_prompt printed %q and %v without a context, want nothing and a failure", shell, output, err)
+		}
+	}
+}
+
+func TestPromptCmd(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFlag(t, &os.Stdout, writer)
+	withFlag(t, &promptShell, "zsh")
+	runErr := promptCmd.RunE(promptCmd, nil)
+	writer.Close()
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	if want, _ := promptFunction("zsh"); string(out) != want {
+		t.Errorf("printed\n%s\nwant\n%s", out, want)
+	}
+
+	withFlag(t, &promptShell, "powershell")
+	if err := promptCmd.RunE(promptCmd, nil); err == nil || err.Error() != `unknown shell "powershell", expected bash, zsh or fish` {
+		t.Errorf("got %v for powershell", err)
+	}
+}
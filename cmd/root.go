@@ -5,6 +5,9 @@ import (
 	"os"
 )
 
+// version is the tool version, set at build time with -ldflags "-X github.com/mgdarroch/kube-tools/cmd.version=v1.2.3"
+var version = "dev"
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "kt",
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// secretNamespace is the namespace of the secret, defaulting to the current context's
+	secretNamespace string
+	// secretRedact prints the size of each value instead of the value
+	secretRedact bool
+)
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretViewCmd)
+	secretCmd.AddCommand(secretCreateFromEnvCmd)
+	secretCmd.AddCommand(secretEditCmd)
+	secretCmd.PersistentFlags().StringVarP(&secretNamespace, "namespace", "n", "", "Namespace of the secret (default the current context's)")
+	secretViewCmd.Flags().BoolVar(&secretRedact, "redact", false, "Print the size of each value instead of the value")
+}
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Views and edits secrets with their values decoded",
+}
+
+var secretViewCmd = &cobra.Command{
+	Use:          "view <name> [key...]",
+	Short:        "Prints the secret's keys with their values base64-decoded",
+	Long:         "Prints each data key of the secret, or only the given keys, with its value decoded, in key order.",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := getSecretData(args[0])
+		if err != nil {
+			return err
+		}
+		keys := args[1:]
+		if len(keys) == 0 {
+			for key := range data {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+		}
+		for _, key := range keys {
+			value, ok := data[key]
+			if !ok {
+				return fmt.Errorf("secret %s has no key %q", args[0], key)
+			}
+			switch {
+			case secretRedact:
+				fmt.Printf("%s: (%d bytes)\n", key, len(value))
+			case !utf8.Valid(value):
+				fmt.Printf("%s: (%d bytes of binary data)\n", key, len(value))
+			case bytes.Contains(value, []byte("\n")):
+				fmt.Printf("%s:\n%s\n", key, indent(strings.TrimSuffix(string(value), "\n")))
+			default:
+				fmt.Printf("%s: %s\n", key, value)
+			}
+		}
+		return nil
+	},
+}
+
+var secretCreateFromEnvCmd = &cobra.Command{
+	Use:   "create-from-env <name> <env-file>",
+	Short: "Creates or updates a secret from a KEY=VALUE file",
+	Long: "Creates the secret with a data key for each KEY=VALUE line of the file, or updates an existing" +
+		"\nsecret with the same name through kubectl apply. Blank lines and lines starting with # are skipped.",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		args = append([]string{"create", "secret", "generic", args[0], "--from-env-file=" + args[1], "--dry-run=client", "-o=json"},
+			namespaceFlag(secretNamespace)...)
+		manifest, err := kubectlOutput(args...)
+		if err != nil {
+			return err
+		}
+		return kubectlInput([]byte(manifest), append([]string{"apply", "-f", "-"}, namespaceFlag(secretNamespace)...)...)
+	},
+}
+
+var secretEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Edits the secret's decoded values in $EDITOR and saves them re-encoded",
+	Long: "Opens the secret's data as YAML with its values decoded in $EDITOR, or vi, and patches the secret with" +
+		"\nthe edited values re-encoded once the editor exits. Deleting a key removes it from the secret.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := getSecretData(args[0])
+		if err != nil {
+			return err
+		}
+		decoded := make(map[string]string, len(data))
+		for key, value := range data {
+			if !utf8.Valid(value) {
+				return fmt.Errorf("secret %s: key %q holds binary data, which can't be edited as text", args[0], key)
+			}
+			decoded[key] = string(value)
+		}
+
+		edited, err := editYAML(decoded)
+		if err != nil {
+			return err
+		}
+		patch := make(map[string]any)
+		for key, value := range edited {
+			if previous, ok := decoded[key]; !ok || previous != value {
+				patch[key] = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+		}
+		for key := range decoded {
+			if _, kept := edited[key]; !kept {
+				patch[key] = nil
+			}
+		}
+		if len(patch) == 0 {
+			fmt.Println("no changes")
+			return nil
+		}
+
+		body, err := json.Marshal(map[string]any{"data": patch})
+		if err != nil {
+			return err
+		}
+		if err := patchSecret(args[0], body); err != nil {
+			return err
+		}
+		fmt.Printf("updated %d keys of secret %s\n", len(patch), args[0])
+		return nil
+	},
+}
+
+// patchSecret merges the patch into the secret. The patch holds the new values, so it's passed in a file only the
+// user can read instead of on the command line, where other users could see it in the process list
+func patchSecret(name string, patch []byte) error {
+	file, err := os.CreateTemp("", "kt-secret-patch-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := file.Chmod(0o600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(patch); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	_, err = kubectlOutput(append([]string{"patch", "secret", name, "--type=merge", "--patch-file=" + file.Name()}, namespaceFlag(secretNamespace)...)...)
+	return err
+}
+
+// getSecretData fetches the secret and returns its data with the values decoded
+func getSecretData(name string) (map[string][]byte, error) {
+	output, err := kubectlOutput(append([]string{"get", "secret", name, "-o=json"}, namespaceFlag(secretNamespace)...)...)
+	if err != nil {
+		return nil, err
+	}
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(output), &secret); err != nil {
+		return nil, fmt.Errorf("parsing secret %s: %w", name, err)
+	}
+
+	data := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: key %q isn't valid base64: %w", name, key, err)
+		}
+		data[key] = decoded
+	}
+	return data, nil
+}
+
+// editYAML writes values to a temporary YAML file, opens it in the user's editor and returns the values read back
+func editYAML(values map[string]string) (map[string]string, error) {
+	file, err := os.CreateTemp("", "kt-secret-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	encoder := yaml.NewEncoder(file)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(values); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	// EDITOR may carry arguments, e.g. "code --wait", so it's run by the shell
+	edit := exec.Command("sh", "-c", editor+` "$1"`, "sh", file.Name())
+	edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := edit.Run(); err != nil {
+		return nil, fmt.Errorf("running %s: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	edited := make(map[string]string)
+	if err := yaml.Unmarshal(data, &edited); err != nil {
+		return nil, fmt.Errorf("parsing the edited secret, nothing was saved: %w", err)
+	}
+	return edited, nil
+}
+
+// kubectlInput runs kubectl with the arguments and input on stdin, passing its output through
+func kubectlInput(input []byte, args ...string) error {
+	command := exec.Command("kubectl", args...)
+	command.Stdin = bytes.NewReader(input)
+	command.Stdout = os.Stdout
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("kubectl %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("kubectl %s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeKubectl puts a kubectl running the shell script first on the PATH for the rest of the test, and returns the
+// directory it's in, which the script can reach as $KT_TEST_DIR
+func fakeKubectl(t *testing.T, script string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("KT_TEST_DIR", dir)
+	return dir
+}
+
+func TestPatchSecretKeepsValuesOffTheCommandLine(t *testing.T) {
+	// The fake kubectl records its arguments and keeps a copy of the patch file, mode included
+	dir := fakeKubectl(t, "printf '%s\\n' \"$@\" > \"$KT_TEST_DIR/args\"\n"+
+		"for arg; do case $arg in --patch-file=*) cp -p \"${arg#--patch-file=}\" \"$KT_TEST_DIR/patch\";; esac; done\n")
+
+	patch := `{"data":{"password":"c2VjcmV0"}}`
+	if err := patchSecret("db", []byte(patch)); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(args), "c2VjcmV0") {
+		t.Errorf("the patched value is on the command line:\n%s", args)
+	}
+	info, err := os.Stat(filepath.Join(dir, "patch"))
+	if err != nil {
+		t.Fatalf("kubectl wasn't given a patch file: %v\n%s", err, args)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("the patch file has mode %o, want 600", mode)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "patch")); string(got) != patch {
+		t.Errorf("the patch file holds %s, want %s", got, patch)
+	}
+}
+
+// fakeSecretKubectl puts a kubectl first on the PATH that prints a secret holding password=old, user=admin and
+// token=abc, and keeps a copy of any patch file it's given as $KT_TEST_DIR/patch
+func fakeSecretKubectl(t *testing.T) string {
+	t.Helper()
+	return fakeKubectl(t, `case $1 in
+get) echo '{"data":{"password":"b2xk","user":"YWRtaW4=","token":"YWJj"}}';;
+patch) for arg; do case $arg in --patch-file=*) cp "${arg#--patch-file=}" "$KT_TEST_DIR/patch";; esac; done;;
+esac
+`)
+}
+
+// fakeEditor sets EDITOR to a script that keeps a copy of the file it opens as $KT_TEST_DIR/opened and replaces it
+// with content
+func fakeEditor(t *testing.T, dir, content string) {
+	t.Helper()
+	editor := filepath.Join(dir, "editor")
+	script := "#!/bin/sh\ncp \"$1\" \"$KT_TEST_DIR/opened\"\nprintf '%s' '" + content + "' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+}
+
+func TestSecretEditRoundTrip(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	withFlag(t, &os.Stdout, devNull)
+	tests := []struct {
+		name   string
+		edited string
+		want   map[string]any
+	}{
+		{
+			// A changed value is re-encoded, a deleted key is removed and an added key is encoded, while the
+			// unchanged user is left out of the patch
+			name:   "changes",
+			edited: "password: new\nuser: admin\nhost: db\n",
+			want:   map[string]any{"data": map[string]any{"password": "bmV3", "token": nil, "host": "ZGI="}},
+		},
+		{name: "no changes", edited: "password: old\ntoken: abc\nuser: admin\n"},
+	}
+	for _, test := range tests {
+		dir := fakeSecretKubectl(t)
+		fakeEditor(t, dir, test.edited)
+		if err := secretEditCmd.RunE(secretEditCmd, []string{"db"}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		// The editor is given the values decoded
+		if opened, _ := os.ReadFile(filepath.Join(dir, "opened")); string(opened) != "password: old\ntoken: abc\nuser: admin\n" {
+			t.Errorf("%s: the editor opened %q", test.name, opened)
+		}
+		patch, err := os.ReadFile(filepath.Join(dir, "patch"))
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%s: the secret was patched with %s", test.name, patch)
+			}
+			continue
+		}
+		var got map[string]any
+		if err := json.Unmarshal(patch, &got); err != nil {
+			t.Fatalf("%s: %v\n%s", test.name, err, patch)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got patch %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSecretEditRejectsInvalidYAML(t *testing.T) {
+	dir := fakeSecretKubectl(t)
+	fakeEditor(t, dir, "password: [unclosed\n")
+	err := secretEditCmd.RunE(secretEditCmd, []string{"db"})
+	if err == nil || !strings.Contains(err.Error(), "nothing was saved") {
+		t.Errorf("got %v, want a parse error", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "patch")); err == nil {
+		t.Error("the secret was patched from a file that doesn't parse")
+	}
+}
+
+func TestGetSecretData(t *testing.T) {
+	fakeSecretKubectl(t)
+	got, err := getSecretData("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]byte{"password": []byte("old"), "user": []byte("admin"), "token": []byte("abc")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	fakeKubectl(t, `echo '{"data":{"password":"not base64!"}}'`)
+	if _, err := getSecretData("db"); err == nil || !strings.Contains(err.Error(), `key "password" isn't valid base64`) {
+		t.Errorf("got %v, want an error for the invalid value", err)
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	aliasesCmd.AddCommand(selfTestCmd)
+}
+
+var selfTestCmd = &cobra.Command{
+	Use:   "self-test",
+	Short: "Checks the built-in alias configuration",
+	Long: "Validates the built-in parts the aliases are generated from, checks for aliases that expand to more than" +
+		"\none command and runs a generation smoke test, reporting pass/fail for each check. The config file and" +
+		"\nthe aliases flags are ignored, every opt-in resource group and tool is checked.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checks, failed := selfTest(os.Stdout)
+		if failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, checks)
+		}
+		fmt.Println("all checks passed")
+		return nil
+	},
+}
+
+// builtInGenerators returns the shipped alias trees by name: kubectl's defaults, kubectl's with every opt-in
+// resource group added, and each tool's
+func builtInGenerators() ([]string, map[string]aliases.Generator) {
+	optIn := aliases.Default()
+	for _, group := range [][]aliases.Part{
+		aliases.DeprecatedResources(),
+		aliases.AdvancedResources(),
+		aliases.AuthResources(),
+		aliases.CertManagerResources(),
+		aliases.VPAResources(),
+	} {
+		optIn.Resources = append(optIn.Resources, group...)
+	}
+	optIn.Ops = append(optIn.Ops, aliases.ForceOperations()...)
+	optIn.PosArgs = aliases.PositionalArgs(aliases.ResourceTypes(optIn.Resources))
+	optIn.Convenience = append(aliases.RBACAliases(), aliases.ExploreAliases()...)
+
+	names := []string{"kubectl", "kubectl opt-in"}
+	generators := map[string]aliases.Generator{"kubectl": aliases.Default(), "kubectl opt-in": optIn}
+	for _, tool := range toolNames() {
+		if build, ok := aliases.Tools[tool]; ok {
+			names = append(names, tool)
+			generators[tool] = build()
+		}
+	}
+	return names, generators
+}
+
+// selfTest runs every check against the built-in generators, reporting each to out, and returns the number of
+// checks run and failed
+func selfTest(out io.Writer) (int, int) {
+	checks, failed := 0, 0
+	check := func(name string, err error) {
+		checks++
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL %s\n%s\n", name, indent(err.Error()))
+			return
+		}
+		fmt.Fprintf(out, "PASS %s\n", name)
+	}
+
+	names, generators := builtInGenerators()
+	for _, name := range names {
+		g := generators[name]
+		generated := g.Generate()
+		check(name+" validate", g.Validate())
+		check(name+" collisions", collisionError(aliases.FindCollisions(generated)))
+		check(name+" duplicates", duplicateError(aliases.FindDuplicates(generated)))
+		check(name+" generate", generationError(generated))
+	}
+	return checks, failed
+}
+
+// collisionError describes each colliding alias, or returns nil if there are none
+func collisionError(collisions map[string][]string) error {
+	if len(collisions) == 0 {
+		return nil
+	}
+	var names []string
+	for name := range collisions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s expands to: %s", name, strings.Join(collisions[name], " | ")))
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+// duplicateError describes each command more than one alias expands to, or returns nil if there are none
+func duplicateError(duplicates map[string][]string) error {
+	if len(duplicates) == 0 {
+		return nil
+	}
+	var commands []string
+	for command := range duplicates {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+
+	var lines []string
+	for _, command := range commands {
+		lines = append(lines, fmt.Sprintf("%s is the expansion of: %s", command, strings.Join(duplicates[command], ", ")))
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+// generationError checks that generation produced aliases with non-empty names and commands
+func generationError(aliases []Alias) error {
+	if len(aliases) == 0 {
+		return fmt.Errorf("no aliases were generated")
+	}
+	for _, alias := range aliases {
+		if alias.Name == "" || alias.Command == "" {
+			return fmt.Errorf("generated an empty alias: %q=%q", alias.Name, alias.Command)
+		}
+	}
+	return nil
+}
+
+// indent prefixes each line of text with two spaces
+func indent(text string) string {
+	return "  " + strings.ReplaceAll(text, "\n", "\n  ")
+}
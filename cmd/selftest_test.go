@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSelfTestPassesForDefaults(t *testing.T) {
+	// A broken config mustn't fail the self-test, which only checks the built-in parts
+	withConfig(t, "resources:\n  - alias: rs\n    full: replicasets\n    allowWhenOneOf: [missing]\n")
+
+	var out bytes.Buffer
+	checks, failed := selfTest(&out)
+	if failed > 0 {
+		t.Fatalf("%d of %d checks failed:\n%s", failed, checks, out.String())
+	}
+	for _, tool := range toolNames() {
+		if !strings.Contains(out.String(), "PASS "+tool+" generate") {
+			t.Errorf("%s wasn't checked:\n%s", tool, out.String())
+		}
+	}
+}
+
+func TestGenerationError(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases []Alias
+		wantErr bool
+	}{
+		{"some aliases", []Alias{{Name: "kg", Command: "kubectl get"}}, false},
+		{"no aliases", nil, true},
+		{"empty name", []Alias{{Command: "kubectl get"}}, true},
+		{"empty command", []Alias{{Name: "kg"}}, true},
+	}
+	for _, test := range tests {
+		if err := generationError(test.aliases); (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestDuplicateError(t *testing.T) {
+	if err := duplicateError(nil); err != nil {
+		t.Errorf("got %v for no duplicates", err)
+	}
+	err := duplicateError(map[string][]string{"kubectl create -o=yaml -f": {"kcrtr", "kcrsar"}})
+	if err == nil || err.Error() != "kubectl create -o=yaml -f is the expansion of: kcrtr, kcrsar" {
+		t.Errorf("got %v", err)
+	}
+}
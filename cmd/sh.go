@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// shFallback starts bash when the container has it, and sh otherwise
+const shFallback = "if command -v bash > /dev/null 2>&1; then exec bash; else exec sh; fi"
+
+var (
+	// shNamespace is the namespace of the pods, defaulting to the current context's
+	shNamespace string
+	// shSelector is a label selector the pods have to match
+	shSelector string
+	// shContainer is the container to exec into, defaulting to the pod's default container
+	shContainer string
+)
+
+func init() {
+	rootCmd.AddCommand(shCmd)
+	shCmd.Flags().StringVarP(&shNamespace, "namespace", "n", "", "Namespace of the pods (default the current context's)")
+	shCmd.Flags().StringVarP(&shSelector, "selector", "l", "", "Label selector the pods have to match, e.g. app=api")
+	shCmd.Flags().StringVarP(&shContainer, "container", "c", "", "Container to exec into (default the pod's default container)")
+}
+
+var shCmd = &cobra.Command{
+	Use:   "sh [pattern]",
+	Short: "Opens a shell in a running pod picked by a fuzzy pattern",
+	Long: "Lists the running pods matching --selector and the pattern, lets you pick one, in fzf when it's installed" +
+		"\nor else from a numbered list, and opens an interactive shell in it, bash if the container has it and sh" +
+		"\notherwise. A pod named exactly like the pattern, or the only match, is picked without asking. The pattern" +
+		"\nmatches names containing it, or failing that names containing its letters in order, so apisrv matches" +
+		"\napi-server-5d8c9.",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pattern := ""
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("sh needs kubectl on the PATH")
+		}
+		pods, err := runningPods()
+		if err != nil {
+			return err
+		}
+		matches := matchPods(pods, pattern)
+		if len(matches) == 0 {
+			if pattern == "" {
+				return fmt.Errorf("no running pods")
+			}
+			return fmt.Errorf("no running pod matches %q", pattern)
+		}
+
+		pod := matches[0]
+		if len(matches) > 1 {
+			if pod, err = pickPod(matches); err != nil || pod == "" {
+				return err
+			}
+		}
+
+		execArgs := append([]string{"exec", "-i", "-t", pod}, namespaceFlag(shNamespace)...)
+		if shContainer != "" {
+			execArgs = append(execArgs, "--container="+shContainer)
+		}
+		execArgs = append(execArgs, "--", "sh", "-c", shFallback)
+		session := exec.Command("kubectl", execArgs...)
+		session.Stdin, session.Stdout, session.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := session.Run(); err != nil {
+			var exitErr *exec.ExitError
+			// kubectl has already reported why the session ended, so only its status is passed on
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			return fmt.Errorf("kubectl exec failed: %w", err)
+		}
+		return nil
+	},
+}
+
+// runningPods returns the names of the running pods matching the selector
+func runningPods() ([]string, error) {
+	args := append([]string{"get", "pods", "--field-selector=status.phase=Running", "-o=name"}, namespaceFlag(shNamespace)...)
+	if shSelector != "" {
+		args = append(args, "--selector="+shSelector)
+	}
+	output, err := kubectlOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+	var pods []string
+	for _, name := range strings.Fields(output) {
+		pods = append(pods, strings.TrimPrefix(name, "pod/"))
+	}
+	return pods, nil
+}
+
+// matchPods returns the pod named pattern, else the pods containing it, else the pods containing its characters
+// in order, ignoring case
+func matchPods(pods []string, pattern string) []string {
+	pattern = strings.ToLower(pattern)
+	var containing, fuzzy []string
+	for _, pod := range pods {
+		name := strings.ToLower(pod)
+		switch {
+		case name == pattern:
+			return []string{pod}
+		case strings.Contains(name, pattern):
+			containing = append(containing, pod)
+		case isSubsequence(pattern, name):
+			fuzzy = append(fuzzy, pod)
+		}
+	}
+	if len(containing) > 0 {
+		return containing
+	}
+	return fuzzy
+}
+
+// isSubsequence reports whether every character of pattern appears in s, in the same order
+func isSubsequence(pattern, s string) bool {
+	for _, r := range pattern {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+// pickPod lets the user choose one of the pods, returning "" if the picker was cancelled
+func pickPod(pods []string) (string, error) {
+	if _, err := exec.LookPath("fzf"); err == nil {
+		return runFzf(strings.Join(pods, "\n")+"\n", "--prompt=pod> ", "--height=40%", "--reverse")
+	}
+
+	for i, pod := range pods {
+		fmt.Printf("%3d  %s\n", i+1, pod)
+	}
+	fmt.Printf("Pick a pod [1-%d]: ", len(pods))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	choice, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || choice < 1 || choice > len(pods) {
+		return "", fmt.Errorf("no pod picked")
+	}
+	return pods[choice-1], nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPods(t *testing.T) {
+	pods := []string{"api-server-5d8c9", "api", "api-worker-7f2b1", "web-api-6c4d2", "grafana-0", "Ärger-1", "db-primary"}
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"exact match short-circuits the substring matches", "api", []string{"api"}},
+		{"exact match ignores case", "API", []string{"api"}},
+		{"substring matches", "worker", []string{"api-worker-7f2b1"}},
+		{"substring matches in pod order", "api-", []string{"api-server-5d8c9", "api-worker-7f2b1", "web-api-6c4d2"}},
+		// api-worker-7f2b1 has a then f in order, but grafana-0 contains af, so only it's picked
+		{"substring matches take priority over fuzzy ones", "af", []string{"grafana-0"}},
+		{"fuzzy matches when nothing contains the pattern", "apisrv", []string{"api-server-5d8c9"}},
+		{"fuzzy matches ignore case", "APISRV", []string{"api-server-5d8c9"}},
+		{"fuzzy matches keep the order", "dbp", []string{"db-primary"}},
+		{"multibyte substring", "ärger", []string{"Ärger-1"}},
+		{"multibyte fuzzy", "Äg1", []string{"Ärger-1"}},
+		{"nothing matches", "xyz", nil},
+		{"empty pattern matches every pod", "", pods},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := matchPods(pods, test.pattern); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("matchPods(%q) = %q, want %q", test.pattern, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsSubsequence(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"", "api", true},
+		{"api", "api", true},
+		{"asv", "api-server", true},
+		{"vsa", "api-server", false},
+		{"aa", "api", false},
+		{"aa", "api-a", true},
+		{"api", "", false},
+		{"äö", "bär-möbel", true},
+		{"öä", "bär-möbel", false},
+		// A multibyte rune is only matched whole, never by one of its bytes
+		{"\xc3", "ä", false},
+		{"日本", "日-x-本", true},
+	}
+	for _, test := range tests {
+		if got := isSubsequence(test.pattern, test.s); got != test.want {
+			t.Errorf("isSubsequence(%q, %q) = %v, want %v", test.pattern, test.s, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	rootCmd.AddCommand(ctxCmd)
+	rootCmd.AddCommand(nsCmd)
+}
+
+var ctxCmd = &cobra.Command{
+	Use:   "ctx [context|-]",
+	Short: "Lists or switches the current kubeconfig context",
+	Long: "Lists the kubeconfig contexts, marking the current one, or switches to the context matching the argument." +
+		"\nA context is matched by its exact name or else by a unique substring, and - switches back to the previous one.",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return contextSwitcher.run(args)
+	},
+}
+
+var nsCmd = &cobra.Command{
+	Use:   "ns [namespace|-]",
+	Short: "Lists or switches the namespace of the current context",
+	Long: "Lists the cluster's namespaces, marking the current one, or sets the current context's namespace to the one" +
+		"\nmatching the argument. A namespace is matched by its exact name or else by a unique substring, and - switches" +
+		"\nback to the previous one.",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return namespaceSwitcher.run(args)
+	},
+}
+
+// switcher lists and switches one kubeconfig setting, remembering the previous value for -
+type switcher struct {
+	kind    string
+	list    func() ([]string, error)
+	current func() (string, error)
+	use     func(name string) error
+	// scope returns what the previous value is kept per, such as the context a namespace belongs to, when set
+	scope func() (string, error)
+}
+
+var contextSwitcher = switcher{
+	kind: "context",
+	list: func() ([]string, error) {
+		output, err := kubectlOutput("config", "get-contexts", "-o=name")
+		return strings.Fields(output), err
+	},
+	current: func() (string, error) {
+		return kubectlOutput("config", "current-context")
+	},
+	use: func(name string) error {
+		_, err := kubectlOutput("config", "use-context", name)
+		return err
+	},
+}
+
+var namespaceSwitcher = switcher{
+	kind: "namespace",
+	list: func() ([]string, error) {
+		output, err := kubectlOutput("get", "namespaces", "-o=jsonpath={.items[*].metadata.name}")
+		return strings.Fields(output), err
+	},
+	current: func() (string, error) {
+		namespace, err := kubectlOutput("config", "view", "--minify", "-o=jsonpath={..namespace}")
+		if namespace == "" {
+			namespace = "default"
+		}
+		return namespace, err
+	},
+	use: func(name string) error {
+		_, err := kubectlOutput("config", "set-context", "--current", "--namespace="+name)
+		return err
+	},
+	// Each context has its own namespace, so switching back after changing context picks the context's previous one
+	scope: func() (string, error) {
+		return kubectlOutput("config", "current-context")
+	},
+}
+
+// run lists the values when no argument is given, and otherwise switches to the matching or previous one. The
+// current value is only marked and remembered when it can be read, as with no current context set listing and
+// switching contexts is what's needed
+func (s switcher) run(args []string) error {
+	if len(args) == 0 {
+		names, err := s.list()
+		if err != nil {
+			return err
+		}
+		current, _ := s.current()
+		for _, name := range names {
+			if name == current {
+				fmt.Printf("* %s\n", name)
+			} else {
+				fmt.Printf("  %s\n", name)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	target := args[0]
+	if target == "-" {
+		if target, err = s.previous(); err != nil {
+			return err
+		}
+	} else {
+		names, err := s.list()
+		if err != nil {
+			return err
+		}
+		if target, err = s.match(names, target); err != nil {
+			return err
+		}
+	}
+
+	current, currentErr := s.current()
+	if err := s.use(target); err != nil {
+		return err
+	}
+	if currentErr == nil {
+		if err := s.remember(current); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("switched %s to %s\n", s.kind, target)
+	return nil
+}
+
+// match returns the name equal to query, or else the only name containing it
+func (s switcher) match(names []string, query string) (string, error) {
+	var matches []string
+	for _, name := range names {
+		if name == query {
+			return name, nil
+		}
+		if strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s matches %q", s.kind, query)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q matches more than one %s: %s", query, s.kind, strings.Join(matches, ", "))
+	}
+}
+
+// statePath returns the file the previous value is kept in, one per scope for a scoped setting. The scope is
+// escaped, as context names such as EKS ARNs contain slashes
+func (s switcher) statePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if s.scope == nil {
+		return filepath.Join(home, ".kube-tools", "previous-"+s.kind), nil
+	}
+	scope, err := s.scope()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube-tools", "previous-"+s.kind+"s", url.PathEscape(scope)), nil
+}
+
+// previous returns the value that was current before the last switch
+func (s switcher) previous() (string, error) {
+	path, err := s.statePath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no previous %s to switch back to", s.kind)
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+// remember records the value being switched away from, for -
+func (s switcher) remember(name string) error {
+	path, err := s.statePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(name+"\n"))
+}
+
+// namespaceFlag returns the kubectl flag selecting the namespace, or none for the current context's
+func namespaceFlag(namespace string) []string {
+	if namespace == "" {
+		return nil
+	}
+	return []string{"--namespace=" + namespace}
+}
+
+// kubectlOutput runs kubectl with the arguments and returns its trimmed output
+func kubectlOutput(args ...string) (string, error) {
+	output, err := exec.Command("kubectl", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("kubectl %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("kubectl %s failed: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSwitcherMatch(t *testing.T) {
+	names := []string{"prod-eu", "prod-us", "staging", "dev"}
+	tests := []struct {
+		query string
+		want  string
+		err   bool
+	}{
+		{query: "staging", want: "staging"},
+		{query: "STAG", want: "staging"},
+		{query: "us", want: "prod-us"},
+		{query: "prod", err: true},
+		{query: "qa", err: true},
+	}
+	s := switcher{kind: "context"}
+	for _, test := range tests {
+		got, err := s.match(names, test.query)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("%s: got %q, %v, want %q and an error %v", test.query, got, err, test.want, test.err)
+		}
+	}
+
+	// An exact name wins over the longer names containing it
+	if got, err := s.match([]string{"prod-eu", "prod"}, "prod"); got != "prod" || err != nil {
+		t.Errorf("got %q, %v, want the exact match", got, err)
+	}
+}
+
+// fakeSwitcher is a switcher over an in-memory setting, recording the values it switches to
+type fakeSwitcher struct {
+	names   []string
+	value   string
+	context string
+	used    []string
+}
+
+func (f *fakeSwitcher) switcher(scoped bool) switcher {
+	s := switcher{
+		kind: "namespace",
+		list: func() ([]string, error) { return f.names, nil },
+		current: func() (string, error) {
+			if f.value == "" {
+				return "", errors.New("current-context is not set")
+			}
+			return f.value, nil
+		},
+		use: func(name string) error {
+			f.value = name
+			f.used = append(f.used, name)
+			return nil
+		},
+	}
+	if scoped {
+		s.scope = func() (string, error) { return f.context, nil }
+	}
+	return s
+}
+
+func TestSwitcherPrevious(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	f := &fakeSwitcher{names: []string{"default", "payments", "web"}, value: "default"}
+	s := f.switcher(false)
+	if _, err := s.previous(); err == nil {
+		t.Error("got a previous value before any switch")
+	}
+	for _, arg := range []string{"pay", "-", "-"} {
+		if err := s.run([]string{arg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"payments", "default", "payments"}; !slices.Equal(f.used, want) {
+		t.Errorf("switched to %v, want %v", f.used, want)
+	}
+}
+
+func TestSwitcherWithoutCurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	f := &fakeSwitcher{names: []string{"prod", "staging"}}
+	s := f.switcher(false)
+
+	// Listing and switching work with nothing current, there's just nothing to go back to
+	if err := s.run(nil); err != nil {
+		t.Errorf("listing: %v", err)
+	}
+	if err := s.run([]string{"prod"}); err != nil || f.value != "prod" {
+		t.Errorf("switching: got %q, %v", f.value, err)
+	}
+	if err := s.run([]string{"-"}); err == nil {
+		t.Errorf("switched back to %q, with no previous value", f.value)
+	}
+}
+
+func TestSwitcherPreviousPerContext(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	f := &fakeSwitcher{names: []string{"default", "payments", "web"}, value: "default"}
+	s := f.switcher(true)
+
+	f.context = "arn:aws:eks:eu-west-1:123:cluster/prod"
+	if err := s.run([]string{"payments"}); err != nil {
+		t.Fatal(err)
+	}
+	f.context, f.value = "staging", "web"
+	if err := s.run([]string{"payments"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each context switches back to its own previous namespace
+	for context, want := range map[string]string{"arn:aws:eks:eu-west-1:123:cluster/prod": "default", "staging": "web"} {
+		f.context = context
+		if got, err := s.previous(); got != want || err != nil {
+			t.Errorf("%s: got %q, %v, want %q", context, got, err, want)
+		}
+	}
+	f.context = "dev"
+	if _, err := s.previous(); err == nil {
+		t.Error("got a previous namespace for a context that never switched")
+	}
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"sort"
+)
+
+// toolNames returns the tools that can be selected with --tools, in order
+func toolNames() []string {
+	names := []string{"kubectl"}
+	for name := range aliases.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
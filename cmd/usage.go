@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// quantitySuffixes maps the suffixes of Kubernetes resource quantities to their multiplier
+var quantitySuffixes = map[string]float64{
+	"n": 1e-9, "u": 1e-6, "m": 1e-3,
+	"k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+}
+
+var (
+	// usageBy selects the rows of the overview: namespace, node or pod
+	usageBy string
+	// usageNamespace limits the pod overview to a namespace, defaulting to the current context's
+	usageNamespace string
+	// usageAllNamespaces shows the pods of every namespace in the pod overview
+	usageAllNamespaces bool
+	// usageIdlePercent is the share of its request below which a pod's usage is noted as over-provisioned
+	usageIdlePercent int
+)
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().StringVar(&usageBy, "by", "namespace", "Rows of the overview, one of: namespace, node, pod")
+	usageCmd.Flags().StringVarP(&usageNamespace, "namespace", "n", "", "Namespace of the pods with --by pod (default the current context's)")
+	usageCmd.Flags().BoolVarP(&usageAllNamespaces, "all-namespaces", "A", false, "Show the pods of every namespace with --by pod")
+	usageCmd.Flags().IntVar(&usageIdlePercent, "idle-percent", 25, "Usage, as a percentage of the request, below which a pod is noted as over-provisioned")
+}
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Compares actual CPU and memory usage with requests and limits",
+	Long: "Combines metrics-server usage from kubectl top with the requests and limits of the running pods, summed" +
+		"\nper namespace, per node next to its allocatable capacity, or listed per pod with a note on pods using more" +
+		"\nthan they request or less than --idle-percent of it. Without metrics-server the usage columns are empty.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if usageBy != "namespace" && usageBy != "node" && usageBy != "pod" {
+			return fmt.Errorf("unknown --by %q, expected namespace, node or pod", usageBy)
+		}
+		if usageAllNamespaces && usageNamespace != "" {
+			return fmt.Errorf("--namespace and --all-namespaces can't be used together")
+		}
+		// Namespace and node totals need every pod, only the pod list can be narrowed down
+		scope := []string{"--all-namespaces"}
+		if usageBy == "pod" && !usageAllNamespaces {
+			scope = namespaceFlag(usageNamespace)
+		}
+
+		pods, err := getPodResources(scope)
+		if err != nil {
+			return err
+		}
+		measured := true
+		if err := addPodUsage(pods, scope); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: showing requests and limits only, %v\n", err)
+			measured = false
+		}
+
+		out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		switch usageBy {
+		case "namespace":
+			writeNamespaceUsage(out, pods, measured)
+		case "node":
+			allocatable, err := getNodeAllocatable()
+			if err != nil {
+				return err
+			}
+			writeNodeUsage(out, pods, allocatable, measured)
+		case "pod":
+			writePodUsage(out, pods, measured)
+		}
+		return out.Flush()
+	},
+}
+
+// quantities holds a CPU amount in cores and a memory amount in bytes
+type quantities struct {
+	CPU    float64
+	Memory float64
+}
+
+func (q *quantities) add(other quantities) {
+	q.CPU += other.CPU
+	q.Memory += other.Memory
+}
+
+// podUsage is a running pod's requests and limits, summed over its containers, and its measured usage
+type podUsage struct {
+	Namespace string
+	Name      string
+	Node      string
+	Requests  quantities
+	Limits    quantities
+	Usage     quantities
+}
+
+// getPodResources fetches the pods that are scheduled and not finished, with their effective requests and limits
+func getPodResources(scope []string) ([]*podUsage, error) {
+	output, err := kubectlOutput(append([]string{"get", "pods", "-o=json"}, scope...)...)
+	if err != nil {
+		return nil, err
+	}
+	type containerResources struct {
+		Resources struct {
+			Requests map[string]string `json:"requests"`
+			Limits   map[string]string `json:"limits"`
+		} `json:"resources"`
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName       string               `json:"nodeName"`
+				Containers     []containerResources `json:"containers"`
+				InitContainers []containerResources `json:"initContainers"`
+			} `json:"spec"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("parsing kubectl get pods output: %w", err)
+	}
+
+	var pods []*podUsage
+	for _, item := range list.Items {
+		if item.Spec.NodeName == "" || item.Status.Phase == "Succeeded" || item.Status.Phase == "Failed" {
+			continue
+		}
+		pod := &podUsage{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name, Node: item.Spec.NodeName}
+		for _, container := range item.Spec.Containers {
+			pod.Requests.add(parseQuantities(container.Resources.Requests))
+			pod.Limits.add(parseQuantities(container.Resources.Limits))
+		}
+		// Init containers run one at a time before the others, so only the largest can raise the pod's amounts
+		for _, container := range item.Spec.InitContainers {
+			requests, limits := parseQuantities(container.Resources.Requests), parseQuantities(container.Resources.Limits)
+			pod.Requests = quantities{max(pod.Requests.CPU, requests.CPU), max(pod.Requests.Memory, requests.Memory)}
+			pod.Limits = quantities{max(pod.Limits.CPU, limits.CPU), max(pod.Limits.Memory, limits.Memory)}
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// addPodUsage fills in the usage of each pod from kubectl top, failing when metrics-server isn't available
+func addPodUsage(pods []*podUsage, scope []string) error {
+	output, err := kubectlOutput(append([]string{"top", "pods", "--no-headers"}, scope...)...)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*podUsage, len(pods))
+	for _, pod := range pods {
+		byName[pod.Namespace+"/"+pod.Name] = pod
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		var namespace string
+		switch len(fields) {
+		case 4:
+			namespace, fields = fields[0], fields[1:]
+		case 3:
+			namespace = ""
+		default:
+			continue
+		}
+		pod, ok := byName[namespace+"/"+fields[0]]
+		if !ok && namespace == "" {
+			// Without --all-namespaces kubectl top leaves the namespace out, which is the only one listed
+			for _, candidate := range pods {
+				if candidate.Name == fields[0] {
+					pod, ok = candidate, true
+				}
+			}
+		}
+		if ok {
+			pod.Usage = quantities{parseQuantity(fields[1]), parseQuantity(fields[2])}
+		}
+	}
+	return nil
+}
+
+// getNodeAllocatable returns each node's allocatable CPU and memory
+func getNodeAllocatable() (map[string]quantities, error) {
+	output, err := kubectlOutput("get", "nodes", "-o=json")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("parsing kubectl get nodes output: %w", err)
+	}
+	allocatable := make(map[string]quantities, len(list.Items))
+	for _, node := range list.Items {
+		allocatable[node.Metadata.Name] = parseQuantities(node.Status.Allocatable)
+	}
+	return allocatable, nil
+}
+
+// writeNamespaceUsage writes the usage, requests and limits summed per namespace
+func writeNamespaceUsage(out *tabwriter.Writer, pods []*podUsage, measured bool) {
+	fmt.Fprintln(out, "NAMESPACE\tPODS\tCPU USED\tCPU REQ\tCPU LIM\tMEM USED\tMEM REQ\tMEM LIM")
+	for _, total := range sumPods(pods, func(pod *podUsage) string { return pod.Namespace }) {
+		fmt.Fprintf(out, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", total.key, total.pods,
+			usedCPU(total.usage, measured), formatCPU(total.requests.CPU), formatCPU(total.limits.CPU),
+			usedMemory(total.usage, measured), formatMemory(total.requests.Memory), formatMemory(total.limits.Memory))
+	}
+}
+
+// writeNodeUsage writes the usage, requests and limits summed per node, with each as a share of what's allocatable
+func writeNodeUsage(out *tabwriter.Writer, pods []*podUsage, allocatable map[string]quantities, measured bool) {
+	fmt.Fprintln(out, "NODE\tPODS\tCPU USED\tCPU REQ\tCPU LIM\tCPU ALLOC\tMEM USED\tMEM REQ\tMEM LIM\tMEM ALLOC")
+	for _, total := range sumPods(pods, func(pod *podUsage) string { return pod.Node }) {
+		capacity := allocatable[total.key]
+		cpuUsed, memoryUsed := "-", "-"
+		if measured {
+			cpuUsed = formatCPU(total.usage.CPU) + share(total.usage.CPU, capacity.CPU)
+			memoryUsed = formatMemory(total.usage.Memory) + share(total.usage.Memory, capacity.Memory)
+		}
+		fmt.Fprintf(out, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", total.key, total.pods,
+			cpuUsed, formatCPU(total.requests.CPU)+share(total.requests.CPU, capacity.CPU),
+			formatCPU(total.limits.CPU)+share(total.limits.CPU, capacity.CPU), formatCPU(capacity.CPU),
+			memoryUsed, formatMemory(total.requests.Memory)+share(total.requests.Memory, capacity.Memory),
+			formatMemory(total.limits.Memory)+share(total.limits.Memory, capacity.Memory), formatMemory(capacity.Memory))
+	}
+}
+
+// writePodUsage writes each pod's usage, requests and limits, noting pods that look under- or over-provisioned
+func writePodUsage(out *tabwriter.Writer, pods []*podUsage, measured bool) {
+	slices.SortFunc(pods, func(a, b *podUsage) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	fmt.Fprintln(out, "NAMESPACE\tPOD\tCPU USED\tCPU REQ\tCPU LIM\tMEM USED\tMEM REQ\tMEM LIM\tNOTE")
+	for _, pod := range pods {
+		note := ""
+		if measured {
+			note = provisioningNote(pod)
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pod.Namespace, pod.Name,
+			usedCPU(pod.Usage, measured), formatCPU(pod.Requests.CPU), formatCPU(pod.Limits.CPU),
+			usedMemory(pod.Usage, measured), formatMemory(pod.Requests.Memory), formatMemory(pod.Limits.Memory), note)
+	}
+}
+
+// provisioningNote describes how the pod's usage compares with its requests: above them means it may be starved
+// or evicted under pressure, well below them means capacity is reserved for nothing
+func provisioningNote(pod *podUsage) string {
+	var notes []string
+	compare := func(resource string, used, requested float64) {
+		switch {
+		case requested == 0:
+			notes = append(notes, "no "+resource+" request")
+		case used > requested:
+			notes = append(notes, resource+" above request")
+		case used < requested*float64(usageIdlePercent)/100:
+			notes = append(notes, resource+" below "+strconv.Itoa(usageIdlePercent)+"% of request")
+		}
+	}
+	compare("cpu", pod.Usage.CPU, pod.Requests.CPU)
+	compare("memory", pod.Usage.Memory, pod.Requests.Memory)
+	return strings.Join(notes, ", ")
+}
+
+// usageTotal sums the pods sharing a key
+type usageTotal struct {
+	key      string
+	pods     int
+	usage    quantities
+	requests quantities
+	limits   quantities
+}
+
+// sumPods returns the totals of the pods grouped by key, ordered by key
+func sumPods(pods []*podUsage, key func(pod *podUsage) string) []*usageTotal {
+	var totals []*usageTotal
+	byKey := make(map[string]*usageTotal)
+	for _, pod := range pods {
+		total, ok := byKey[key(pod)]
+		if !ok {
+			total = &usageTotal{key: key(pod)}
+			byKey[total.key] = total
+			totals = append(totals, total)
+		}
+		total.pods++
+		total.usage.add(pod.Usage)
+		total.requests.add(pod.Requests)
+		total.limits.add(pod.Limits)
+	}
+	slices.SortFunc(totals, func(a, b *usageTotal) int { return strings.Compare(a.key, b.key) })
+	return totals
+}
+
+// parseQuantities returns the cpu and memory entries of a requests, limits or allocatable map
+func parseQuantities(values map[string]string) quantities {
+	return quantities{CPU: parseQuantity(values["cpu"]), Memory: parseQuantity(values["memory"])}
+}
+
+// parseQuantity converts a Kubernetes quantity such as 250m or 128Mi to a plain number, treating anything it
+// can't parse, including an empty quantity, as zero
+func parseQuantity(quantity string) float64 {
+	number := strings.TrimRightFunc(quantity, func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') })
+	multiplier, ok := 1.0, true
+	if suffix := quantity[len(number):]; suffix != "" {
+		multiplier, ok = quantitySuffixes[suffix]
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || !ok {
+		return 0
+	}
+	return value * multiplier
+}
+
+// formatCPU renders cores as millicores, like kubectl top
+func formatCPU(cores float64) string {
+	return fmt.Sprintf("%dm", int64(math.Round(cores*1000)))
+}
+
+// formatMemory renders bytes as mebibytes, like kubectl top
+func formatMemory(bytes float64) string {
+	return fmt.Sprintf("%dMi", int64(math.Round(bytes/(1<<20))))
+}
+
+// usedCPU and usedMemory render the measured usage, or - when there's no metrics-server to measure it
+func usedCPU(usage quantities, measured bool) string {
+	if !measured {
+		return "-"
+	}
+	return formatCPU(usage.CPU)
+}
+
+func usedMemory(usage quantities, measured bool) string {
+	if !measured {
+		return "-"
+	}
+	return formatMemory(usage.Memory)
+}
+
+// share renders amount as a percentage of capacity, in parentheses, or nothing when the capacity is unknown
+func share(amount, capacity float64) string {
+	if capacity == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d%%)", int64(math.Round(amount/capacity*100)))
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := map[string]float64{
+		"":      0,
+		"2":     2,
+		"0.5":   0.5,
+		"250m":  0.25,
+		"1500u": 0.0015,
+		"100n":  1e-7,
+		"1k":    1000,
+		"128M":  128e6,
+		"2G":    2e9,
+		"128Mi": 128 << 20,
+		"1.5Gi": 1.5 * (1 << 30),
+		"1Ti":   1 << 40,
+		"1e3":   1000,
+		"1E3":   1000,
+		"12Xi":  0,
+		"Mi":    0,
+		"lots":  0,
+	}
+	for quantity, want := range tests {
+		// The small suffixes multiply by fractions that floats only approximate
+		if got := parseQuantity(quantity); math.Abs(got-want) > math.Abs(want)*1e-12 {
+			t.Errorf("parseQuantity(%q) = %v, want %v", quantity, got, want)
+		}
+	}
+}
+
+func TestFormatQuantities(t *testing.T) {
+	if got := formatCPU(parseQuantity("1500m")); got != "1500m" {
+		t.Errorf("formatCPU(1.5) = %s, want 1500m", got)
+	}
+	if got := formatMemory(parseQuantity("1Gi")); got != "1024Mi" {
+		t.Errorf("formatMemory(1Gi) = %s, want 1024Mi", got)
+	}
+}
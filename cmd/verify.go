@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+func init() {
+	aliasesCmd.AddCommand(verifyFileCmd)
+}
+
+var verifyFileCmd = &cobra.Command{
+	Use:   "verify-file <file>",
+	Short: "Verifies that a generated alias file is up to date",
+	Long: "Regenerates the aliases with the given flags and compares them against a previously generated file," +
+		"\nignoring its generated header line, printing the differing lines and exiting non-zero if the file is" +
+		"\nout of date. Intended for CI.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		committed, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		generated, err := renderAliases()
+		if err != nil {
+			return err
+		}
+		committed = stripGeneratedHeader(committed)
+		if bytes.Equal(committed, generated) {
+			return nil
+		}
+		fmt.Print(diffLines(string(committed), string(generated)))
+		return fmt.Errorf("%s is out of date, regenerate it with the same flags", args[0])
+	},
+}
+
+// diffLines lists the lines only present in before with a '-' prefix and the lines only present in after with a '+' prefix
+func diffLines(before, after string) string {
+	oldLines := strings.Split(before, "\n")
+	newLines := strings.Split(after, "\n")
+	inOld := make(map[string]struct{}, len(oldLines))
+	for _, line := range oldLines {
+		inOld[line] = struct{}{}
+	}
+	inNew := make(map[string]struct{}, len(newLines))
+	for _, line := range newLines {
+		inNew[line] = struct{}{}
+	}
+
+	var diff strings.Builder
+	for _, line := range oldLines {
+		if _, exists := inNew[line]; !exists {
+			fmt.Fprintf(&diff, "-%s\n", line)
+		}
+	}
+	for _, line := range newLines {
+		if _, exists := inOld[line]; !exists {
+			fmt.Fprintf(&diff, "+%s\n", line)
+		}
+	}
+	if diff.Len() == 0 {
+		diff.WriteString("lines are identical but in a different order\n")
+	}
+	return diff.String()
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	generated := generate(t)
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"matches", generated, false},
+		{"matches with the generated header", generatedHeader(time.Now()) + generated, false},
+		{"an alias was removed", strings.Replace(generated, "alias kgpo='kubectl get pods'\n", "", 1), true},
+		{"an alias changed", strings.Replace(generated, "alias kgpo='kubectl get pods'", "alias kgpo='kubectl get po'", 1), true},
+		{"empty", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aliases.sh")
+			if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := verifyFileCmd.RunE(verifyFileCmd, []string{path})
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want one: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		before, after, want string
+	}{
+		{"a\nb\n", "a\nc\n", "-b\n+c\n"},
+		{"a\nb\n", "b\na\n", "lines are identical but in a different order\n"},
+	}
+	for _, test := range tests {
+		if got := diffLines(test.before, test.after); got != test.want {
+			t.Errorf("diffLines(%q, %q) = %q, want %q", test.before, test.after, got, test.want)
+		}
+	}
+}
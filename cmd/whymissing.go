@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mgdarroch/kube-tools/pkg/aliases"
+	"github.com/spf13/cobra"
+	"slices"
+	"strings"
+)
+
+func init() {
+	aliasesCmd.AddCommand(whyMissingCmd)
+}
+
+var whyMissingCmd = &cobra.Command{
+	Use:   "why-missing <alias>",
+	Short: "Explains why an alias isn't generated",
+	Long: "Splits the alias into the parts it would be built from and reports which AllowWhenOneOf or" +
+		"\nIncompatibleWith rule blocks the combination, or which part of the name no part matches.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ag, err := newAliasGenerator()
+		if err != nil {
+			return err
+		}
+		fmt.Print(ag.explainMissing(args[0]))
+		return nil
+	},
+}
+
+// explainMissing describes, for every way the name can be split into parts of the kubectl tree or a tool's, whether
+// that combination is generated or which rule blocks it
+func (ag *AliasGenerator) explainMissing(name string) string {
+	base, found := strings.CutSuffix(name, ag.Suffix)
+	if !found {
+		return fmt.Sprintf("%s doesn't end with %q, which every alias ends with\n", name, ag.Suffix)
+	}
+
+	type candidate struct {
+		generator *aliases.Generator
+		parts     []Part
+	}
+	var candidates []candidate
+	matched := 0
+	for _, g := range generatorTree(&ag.Generator) {
+		for _, cmd := range g.Commands {
+			for _, extra := range g.Convenience {
+				if cmd.Alias+extra.Alias == base {
+					return fmt.Sprintf("%s is generated as a convenience alias: %s %s\n", name, cmd.Full, extra.Full)
+				}
+			}
+		}
+		decompositions, prefix := decompose(g, base)
+		for _, parts := range decompositions {
+			candidates = append(candidates, candidate{g, parts})
+		}
+		matched = max(matched, prefix)
+	}
+	if len(candidates) == 0 {
+		if matched == 0 {
+			return fmt.Sprintf("%s doesn't start with any command alias\n", name)
+		}
+		for _, g := range generatorTree(&ag.Generator) {
+			if g.IsCompact() && strings.HasPrefix(base[matched:], g.Resources[0].Alias) {
+				return fmt.Sprintf("no part matches %q after %q, compact mode leaves %s out of the names\n", base[matched:], base[:matched], g.Resources[0].Alias)
+			}
+		}
+		return fmt.Sprintf("no part matches %q after %q\n", base[matched:], base[:matched])
+	}
+
+	var explanation strings.Builder
+	for _, candidate := range candidates {
+		var names, fulls []string
+		for _, part := range candidate.parts {
+			// A compact alias's resource isn't spelt in its name
+			if candidate.generator.IsCompact() && slices.ContainsFunc(candidate.generator.Resources, func(p Part) bool { return samePart(p, part) }) {
+				names = append(names, "("+part.Alias+")")
+			} else {
+				names = append(names, part.Alias)
+			}
+			fulls = append(fulls, part.Full)
+		}
+		fmt.Fprintf(&explanation, "%s (%s):\n", strings.Join(names, " + "), strings.Join(fulls, " "))
+
+		if reason := blockingRule(candidate.generator, candidate.parts); reason != "" {
+			fmt.Fprintf(&explanation, "  blocked: %s\n", reason)
+			continue
+		}
+		fmt.Fprintf(&explanation, "  valid, %s is generated\n", name)
+	}
+	return explanation.String()
+}
+
+// generatorTree returns the generator followed by every tool's, in generation order
+func generatorTree(g *aliases.Generator) []*aliases.Generator {
+	tree := []*aliases.Generator{g}
+	for _, tool := range g.Tools {
+		tree = append(tree, generatorTree(tool)...)
+	}
+	return tree
+}
+
+// decompose returns every way name can be spelled by one of the generator's commands followed by at most one part
+// from each later group, along with the length of the longest prefix that could be matched. In compact mode the
+// resource is tried both implied, without taking any of the name, and left out
+func decompose(g *aliases.Generator, name string) ([][]Part, int) {
+	var groups [][]Part
+	for _, group := range g.Stages()[aliases.StageGlobalOps:] {
+		groups = append(groups, *group)
+	}
+	implied := aliases.StageResources - aliases.StageGlobalOps
+	var decompositions [][]Part
+	matched := 0
+
+	var walk func(rest string, stage int, current []Part)
+	walk = func(rest string, stage int, current []Part) {
+		matched = max(matched, len(name)-len(rest))
+		if stage == len(groups) {
+			if rest == "" {
+				decompositions = append(decompositions, current)
+			}
+			return
+		}
+		for _, part := range groups[stage] {
+			if stage == implied && g.IsCompact() {
+				walk(rest, stage+1, append(slices.Clone(current), part))
+			} else if part.Alias != "" && strings.HasPrefix(rest, part.Alias) {
+				walk(rest[len(part.Alias):], stage+1, append(slices.Clone(current), part))
+			}
+		}
+		walk(rest, stage+1, current)
+	}
+
+	for _, cmd := range g.Commands {
+		if strings.HasPrefix(name, cmd.Alias) {
+			walk(name[len(cmd.Alias):], 0, []Part{cmd})
+		}
+	}
+	return decompositions, matched
+}
+
+// blockingRule checks each step of the combination against the generator's validity rules and describes the
+// first rule that rejects it, or returns "" if the combination is valid
+func blockingRule(g *aliases.Generator, parts []Part) string {
+	for i := 1; i < len(parts); i++ {
+		current, next := parts[:i], parts[i]
+		switch g.RejectionReason(current, next) {
+		case aliases.RejectIncompatibleWith:
+			for _, part := range current {
+				if g.Excludes(part, next) {
+					return incompatibleRule(part, next)
+				}
+				if g.Excludes(next, part) {
+					return incompatibleRule(next, part)
+				}
+			}
+		case aliases.RejectAllowWhenOneOf:
+			return fmt.Sprintf("%s is only allowed with one of %s (AllowWhenOneOf)", next.Alias, strings.Join(next.AllowWhenOneOf, ", "))
+		}
+	}
+
+	// Compact mode only leaves the resource out where it can't combine
+	if g.IsCompact() && !slices.ContainsFunc(parts, func(part Part) bool { return samePart(part, g.Resources[0]) }) {
+		before := slices.DeleteFunc(slices.Clone(parts), func(part Part) bool {
+			return slices.ContainsFunc(g.Args, func(p Part) bool { return samePart(p, part) }) ||
+				slices.ContainsFunc(g.PosArgs, func(p Part) bool { return samePart(p, part) })
+		})
+		if g.RejectionReason(before, g.Resources[0]) == "" {
+			return fmt.Sprintf("compact mode implies %s wherever it combines, so it can't be left out", g.Resources[0].Alias)
+		}
+	}
+	return ""
+}
+
+// incompatibleRule describes part's IncompatibleWith rule that excludes other
+func incompatibleRule(part, other Part) string {
+	if slices.Contains(part.IncompatibleWith, other.Alias) {
+		return fmt.Sprintf("%s lists %s in IncompatibleWith", part.Alias, other.Alias)
+	}
+	return fmt.Sprintf("%s lists a namespace shortcut in IncompatibleWith, which covers %s", part.Alias, other.Alias)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExplainMissing(t *testing.T) {
+	tests := []struct {
+		name      string
+		shortcuts []string
+		want      string
+	}{
+		{
+			name: "kdpooyaml",
+			want: "k + d + po + oyaml (kubectl describe pods -o=yaml):\n  blocked: oyaml is only allowed with one of g (AllowWhenOneOf)\n",
+		},
+		{
+			name: "kgnon",
+			want: "k + g + no + n (kubectl get nodes --namespace):\n  blocked: no lists n in IncompatibleWith\n",
+		},
+		{
+			name:      "kmongno",
+			shortcuts: []string{"mon=monitoring"},
+			want: "k + mon + g + no (kubectl --namespace=monitoring get nodes):\n" +
+				"  blocked: no lists a namespace shortcut in IncompatibleWith, which covers mon\n",
+		},
+		{
+			// The --all-namespaces all is blocked, but the --all one spelt the same is generated
+			name: "krmpoall",
+			want: "k + rm + po + all (kubectl delete pods --all-namespaces):\n  blocked: all lists rm in IncompatibleWith\n" +
+				"k + rm + po + all (kubectl delete pods --all):\n  valid, krmpoall is generated\n",
+		},
+		{name: "kgpoxyz", want: "no part matches \"xyz\" after \"kgpo\"\n"},
+		{name: "xyz", want: "xyz doesn't start with any command alias\n"},
+	}
+	for _, test := range tests {
+		withFlag(t, &namespaceShortcuts, test.shortcuts)
+		ag, err := buildAliasGenerator(Config{}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ag.explainMissing(test.name); got != test.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestExplainMissingRenamedAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		suffix  string
+		tools   []string
+		compact bool
+		want    string
+	}{
+		{name: "kgpo2", suffix: "2", want: "k + g + po (kubectl get pods):\n  valid, kgpo2 is generated\n"},
+		{name: "kgpo", suffix: "2", want: "kgpo doesn't end with \"2\", which every alias ends with\n"},
+		{name: "kgpoxyz2", suffix: "2", want: "no part matches \"xyz\" after \"kgpo\"\n"},
+		{name: "hls", tools: []string{"kubectl", "helm"}, want: "h + ls (helm list):\n  valid, hls is generated\n"},
+		{name: "hls2", suffix: "2", tools: []string{"helm"}, want: "h + ls (helm list):\n  valid, hls2 is generated\n"},
+		{
+			// The implied resource is shown in brackets, and leaving it out where it combines isn't generated
+			name: "kd", compact: true,
+			want: "k + d + (po) (kubectl describe pods):\n  valid, kd is generated\n" +
+				"k + d (kubectl describe):\n  blocked: compact mode implies po wherever it combines, so it can't be left out\n",
+		},
+		{name: "kdpo", compact: true, want: "no part matches \"po\" after \"kd\", compact mode leaves po out of the names\n"},
+	}
+	for _, test := range tests {
+		withFlag(t, &aliasSuffix, test.suffix)
+		withFlag(t, &aliasTools, []string{"kubectl"})
+		if test.tools != nil {
+			withFlag(t, &aliasTools, test.tools)
+		}
+		withFlag(t, &compactAliases, test.compact)
+		withFlag(t, &onlyResources, nil)
+		if test.compact {
+			withFlag(t, &onlyResources, []string{"po"})
+		}
+		ag, err := buildAliasGenerator(Config{}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ag.explainMissing(test.name); got != test.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", test.name, got, test.want)
+		}
+		// Every alias reported as generated is
+		if strings.Contains(test.want, "is generated") && !slices.ContainsFunc(ag.Generate(), func(alias Alias) bool { return alias.Name == test.name }) {
+			t.Errorf("%s is explained as generated but isn't", test.name)
+		}
+	}
+}
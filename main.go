@@ -3,7 +3,7 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 */
 package main
 
-import "kt/cmd"
+import "github.com/mgdarroch/kube-tools/cmd"
 
 func main() {
 	cmd.Execute()
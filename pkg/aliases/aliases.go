@@ -0,0 +1,362 @@
+// Package aliases generates shell aliases for kubectl and similar tools by combining groups of parts, such as
+// operations, resources and flags, into every valid combination. It leaves rendering the aliases to the caller
+package aliases
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Part represents a single part of a command (e.g., an operation or a resource)
+type Part struct {
+	Alias string `yaml:"alias"`
+	Full  string `yaml:"full"`
+	// AllowWhenOneOf names the parts one of which has to come earlier in the alias, and IncompatibleWith the parts
+	// the part is never combined with, whichever of the two comes first
+	AllowWhenOneOf   []string `yaml:"allowWhenOneOf,omitempty"`
+	IncompatibleWith []string `yaml:"incompatibleWith,omitempty"`
+	// Category tags a resource with the set it belongs to, e.g. core or istio, for filtering
+	Category string `yaml:"category,omitempty"`
+	// Display is the readable name docs show for the part, e.g. horizontal pod autoscalers, when its expansion
+	// isn't one. It never changes the command
+	Display string `yaml:"display,omitempty"`
+}
+
+// Alias is a single generated alias and the command it expands to
+type Alias struct {
+	Name       string
+	Command    string
+	Deprecated bool
+	// Function is set when the alias has to be emitted as a shell function
+	Function bool
+	// Operation, Resource and Argument are the expansions of the alias's parts from those groups, if any
+	Operation string
+	Resource  string
+	Argument  string
+	// Steps holds the commands of a chained alias, which run one after the other with the alias's arguments,
+	// stopping at the first that fails. Command joins them with &&
+	Steps []string
+}
+
+// Chain runs several operations one after the other from a single alias, e.g. get then logs
+type Chain struct {
+	// Ops lists the aliases of the chained operations, in the order they run
+	Ops []string `yaml:"ops"`
+}
+
+// Generator holds the part groups aliases are combined from and the options that shape them
+type Generator struct {
+	Commands  []Part
+	GlobalOps []Part
+	Ops       []Part
+	Resources []Part
+	Args      []Part
+	PosArgs   []Part
+	// Convenience holds fixed aliases that don't fit the pipeline, appended directly to each command
+	Convenience []Part
+	// Chains holds the op chains appended to each command. Only the chains listed here are generated, and
+	// those that don't pass ChainError are skipped
+	Chains []Chain
+	// Deprecated holds the aliases of resources that are marked as deprecated in the output
+	Deprecated map[string]struct{}
+	// Compact drops the resource from alias names when there is only one resource
+	Compact bool
+	// Warnings receives a line for each duplicate flag dropped from an alias, when set
+	Warnings io.Writer
+	// SpaceFlags renders baked-in flag values as --flag value instead of --flag=value
+	SpaceFlags bool
+	// DeleteWrapper prefixes the command of delete aliases, which are emitted as functions
+	DeleteWrapper string
+	// Suffix is appended to every alias name
+	Suffix string
+	// Tools holds the independent alias trees of other tools, such as helm, generated after this one's aliases
+	Tools []*Generator
+	// Pruned counts the candidate combinations considered while generating
+	Pruned PruneStats
+}
+
+// Stages of the alias pipeline, in the order their parts are combined into an alias
+const (
+	StageCommands = iota
+	StageGlobalOps
+	StageOps
+	StageResources
+	StageArgs
+	StagePosArgs
+)
+
+// Stages returns the part groups in the order they're combined into an alias, indexed by their stage constant,
+// so a new group only needs a constant and an entry here
+func (g *Generator) Stages() []*[]Part {
+	return []*[]Part{&g.Commands, &g.GlobalOps, &g.Ops, &g.Resources, &g.Args, &g.PosArgs}
+}
+
+// AddGroup appends parts to the group combined at the given stage of the pipeline
+func (g *Generator) AddGroup(stage int, parts []Part) error {
+	stages := g.Stages()
+	if stage < 0 || stage >= len(stages) {
+		return fmt.Errorf("unknown stage %d", stage)
+	}
+	*stages[stage] = append(*stages[stage], parts...)
+	return nil
+}
+
+// Generate returns all valid aliases, in generation order
+func (g *Generator) Generate() []Alias {
+	var aliases []Alias
+	g.Walk(func(alias Alias) bool {
+		aliases = append(aliases, alias)
+		return true
+	})
+	return aliases
+}
+
+// Walk calls fn with each valid alias in generation order, without collecting them, and stops as soon as fn
+// returns false. It reports whether every alias was walked
+func (g *Generator) Walk(fn func(alias Alias) bool) bool {
+	for _, cmd := range g.Commands {
+		if !g.combine([]Part{cmd}, StageGlobalOps, fn) {
+			return false
+		}
+		for _, extra := range g.Convenience {
+			if !fn(g.NewAlias([]Part{cmd, extra})) {
+				return false
+			}
+		}
+		for _, chain := range g.Chains {
+			if g.ChainError(chain) == nil && !fn(g.chainAlias(cmd, chain)) {
+				return false
+			}
+		}
+	}
+	for _, tool := range g.Tools {
+		if !tool.Walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllCommands returns the base commands of this alias tree and of every tool's
+func (g *Generator) AllCommands() []Part {
+	commands := slices.Clone(g.Commands)
+	for _, tool := range g.Tools {
+		commands = append(commands, tool.AllCommands()...)
+	}
+	return commands
+}
+
+// combine recursively extends the combination with each valid part of the stage's group, and with none of them,
+// passing the alias of each valid combination to fn once it's past the last stage. It returns false once fn does
+func (g *Generator) combine(current []Part, stage int, fn func(alias Alias) bool) bool {
+	stages := g.Stages()
+	if stage == len(stages) {
+		return fn(g.NewAlias(current))
+	}
+
+	added := false
+	for _, part := range *stages[stage] {
+		reason := g.RejectionReason(current, part)
+		g.Pruned.record(reason)
+		if reason == "" {
+			if !g.combine(append(current, part), stage+1, fn) {
+				return false
+			}
+			added = true
+		}
+	}
+
+	// In compact mode the resource is implied, so it can't be left out where it applies
+	if added && stage == StageResources && g.IsCompact() {
+		return true
+	}
+
+	// Try without adding a new part from the current group
+	return g.combine(current, stage+1, fn)
+}
+
+// NewAlias builds the alias for the combination of parts
+func (g *Generator) NewAlias(combination []Part) Alias {
+	alias := ""
+	var tokens []string
+	for _, part := range combination {
+		if !g.IsCompact() || !g.isResource(part) {
+			alias += part.Alias
+		}
+		tokens = append(tokens, strings.Fields(part.Full)...)
+	}
+
+	tokens, dropped := dedupeFlags(tokens)
+	if g.Warnings != nil {
+		for _, flag := range dropped {
+			fmt.Fprintf(g.Warnings, "warning: alias %s overrides duplicate flag %s\n", alias, flag)
+		}
+	}
+	if g.SpaceFlags {
+		tokens = splitFlagValues(tokens)
+	}
+	result := Alias{Name: alias + g.Suffix, Command: strings.Join(tokens, " "), Deprecated: g.isDeprecated(combination)}
+	for _, part := range combination {
+		switch {
+		case inGroup(part, g.Ops):
+			result.Operation = part.Full
+		case inGroup(part, g.Resources):
+			result.Resource = part.Full
+		case inGroup(part, g.Args):
+			result.Argument = part.Full
+		}
+	}
+	if g.DeleteWrapper != "" && g.isDelete(combination) {
+		result.Command = g.DeleteWrapper + " " + result.Command
+		result.Function = true
+	}
+	return result
+}
+
+// chainAlias builds the alias running each of the chain's operations in turn. Each step is built like the
+// command and operation alias it runs, so it gets the same flag handling and delete wrapper
+func (g *Generator) chainAlias(cmd Part, chain Chain) Alias {
+	name := cmd.Alias
+	var steps []string
+	for _, alias := range chain.Ops {
+		op := g.Ops[slices.IndexFunc(g.Ops, func(op Part) bool { return op.Alias == alias })]
+		name += op.Alias
+		steps = append(steps, g.NewAlias([]Part{cmd, op}).Command)
+	}
+	return Alias{Name: name + g.Suffix, Command: strings.Join(steps, " && "), Function: true, Steps: steps}
+}
+
+// ChainError reports why the chain can't be generated: it needs at least two operations, each defined and
+// listed once
+func (g *Generator) ChainError(chain Chain) error {
+	if len(chain.Ops) < 2 {
+		return fmt.Errorf("chain %s needs at least two operations", strings.Join(chain.Ops, "+"))
+	}
+	for i, alias := range chain.Ops {
+		if !slices.ContainsFunc(g.Ops, func(op Part) bool { return op.Alias == alias }) {
+			return fmt.Errorf("chain %s references unknown operation %q", strings.Join(chain.Ops, "+"), alias)
+		}
+		if slices.Contains(chain.Ops[:i], alias) {
+			return fmt.Errorf("chain %s lists operation %q more than once", strings.Join(chain.Ops, "+"), alias)
+		}
+	}
+	return nil
+}
+
+// splitFlagValues turns each --flag=value token into separate --flag and value tokens. Parts always
+// write baked-in values with '=', flags left for the user to fill in are already space separated
+func splitFlagValues(tokens []string) []string {
+	var split []string
+	for _, token := range tokens {
+		if flag, value, found := strings.Cut(token, "="); found && strings.HasPrefix(token, "-") {
+			split = append(split, flag, value)
+			continue
+		}
+		split = append(split, token)
+	}
+	return split
+}
+
+// singleValuedFlags maps the flags kubectl only takes one value for to a key shared by their short and long
+// forms. Other flags, like -l or --from-literal, can be repeated
+var singleValuedFlags = map[string]string{
+	"-o":          "--output",
+	"--output":    "--output",
+	"-n":          "--namespace",
+	"--namespace": "--namespace",
+}
+
+// singleValuedKey returns the key of a single-valued flag with a baked-in value, like -o=yaml. A flag without
+// a value, like the n positional's --namespace, is left for the user to fill in and isn't a duplicate of one
+func singleValuedKey(token string) (string, bool) {
+	flag, _, baked := strings.Cut(token, "=")
+	key, single := singleValuedFlags[flag]
+	return key, baked && single
+}
+
+// dedupeFlags removes all but the last baked-in value of each single-valued flag, and returns the remaining tokens
+// along with the dropped flags
+func dedupeFlags(tokens []string) ([]string, []string) {
+	last := make(map[string]int)
+	for i, token := range tokens {
+		if key, ok := singleValuedKey(token); ok {
+			last[key] = i
+		}
+	}
+
+	var kept, dropped []string
+	for i, token := range tokens {
+		if key, ok := singleValuedKey(token); ok && last[key] != i {
+			dropped = append(dropped, token)
+			continue
+		}
+		kept = append(kept, token)
+	}
+	return kept, dropped
+}
+
+// IsCompact reports whether resources should be dropped from alias names
+func (g *Generator) IsCompact() bool {
+	return g.Compact && len(g.Resources) == 1
+}
+
+// isResource reports whether the part is one of the generator's resources
+func (g *Generator) isResource(part Part) bool {
+	return inGroup(part, g.Resources)
+}
+
+// inGroup reports whether the part is one of the parts in group
+func inGroup(part Part, group []Part) bool {
+	for _, candidate := range group {
+		if candidate.Alias == part.Alias && candidate.Full == part.Full {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamespaceShortcut reports whether the part is a global op that only scopes the command to a namespace,
+// like sys for --namespace=kube-system
+func (g *Generator) isNamespaceShortcut(part Part) bool {
+	return inGroup(part, g.GlobalOps) && isNamespaceFlag(part.Full)
+}
+
+// isNamespaceShortcutAlias reports whether alias names one of the generator's namespace shortcuts
+func (g *Generator) isNamespaceShortcutAlias(alias string) bool {
+	for _, op := range g.GlobalOps {
+		if op.Alias == alias && isNamespaceFlag(op.Full) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamespaceFlag reports whether full is a single --namespace flag with its value
+func isNamespaceFlag(full string) bool {
+	value, found := strings.CutPrefix(full, "--namespace=")
+	return found && value != "" && !strings.ContainsAny(value, " \t")
+}
+
+// isDelete reports whether the combination contains the delete operation
+func (g *Generator) isDelete(combination []Part) bool {
+	for _, part := range combination {
+		for _, op := range g.Ops {
+			if part.Alias == op.Alias && part.Full == op.Full && strings.HasPrefix(op.Full, "delete") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDeprecated reports whether the combination contains a deprecated resource
+func (g *Generator) isDeprecated(combination []Part) bool {
+	for _, part := range combination {
+		if _, exists := g.Deprecated[part.Alias]; exists {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,329 @@
+package aliases
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// names returns the names of the aliases, in generation order
+func names(aliases []Alias) []string {
+	var names []string
+	for _, alias := range aliases {
+		names = append(names, alias.Name)
+	}
+	return names
+}
+
+// testGenerator returns a small generator with the get, describe and delete operations and the given resources
+func testGenerator(resources ...Part) Generator {
+	return Generator{
+		Commands: []Part{{Alias: "k", Full: "kubectl"}},
+		Ops: []Part{
+			{Alias: "g", Full: "get"},
+			{Alias: "d", Full: "describe"},
+			{Alias: "rm", Full: "delete"},
+		},
+		Resources: resources,
+	}
+}
+
+func TestCompact(t *testing.T) {
+	pods := Part{Alias: "po", Full: "pods"}
+	services := Part{Alias: "svc", Full: "service"}
+	tests := []struct {
+		name      string
+		resources []Part
+		compact   bool
+		want      []string
+	}{
+		{"single resource collapses", []Part{pods}, true, []string{"kg", "kd", "krm", "k"}},
+		{"single resource without compact", []Part{pods}, false, []string{"kgpo", "kg", "kdpo", "kd", "krmpo", "krm", "kpo", "k"}},
+		{"multiple resources keep their names", []Part{pods, services}, true, []string{"kgpo", "kgsvc", "kg", "kdpo", "kdsvc", "kd", "krmpo", "krmsvc", "krm", "kpo", "ksvc", "k"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := testGenerator(test.resources...)
+			g.Compact = test.compact
+			if got := names(g.Generate()); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompactKeepsCommand(t *testing.T) {
+	g := testGenerator(Part{Alias: "po", Full: "pods"})
+	g.Compact = true
+	for _, alias := range g.Generate() {
+		if alias.Name == "kg" && alias.Command != "kubectl get pods" {
+			t.Errorf("kg expands to %q, want kubectl get pods", alias.Command)
+		}
+	}
+}
+
+func TestFilterResources(t *testing.T) {
+	resources := []Part{{Alias: "po", Full: "pods"}, {Alias: "svc", Full: "service"}, {Alias: "cm", Full: "configmap"}}
+	got := ResourceTypes(FilterResources(resources, []string{"cm", "po", "missing"}))
+	if want := []string{"po", "cm"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// updateGolden rewrites the golden files from the current output instead of comparing against them
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// depthCombine is the combination recursion as it was before the stages drove it, with the groups picked by fixed
+// depths, kept to check the pipeline still generates exactly what it did
+func depthCombine(g *Generator, current []Part, next []Part, depth int) []Alias {
+	if depth == 6 {
+		return []Alias{g.NewAlias(current)}
+	}
+	var aliases []Alias
+	added := false
+	for _, part := range next {
+		if g.RejectionReason(current, part) == "" {
+			aliases = append(aliases, depthNextStep(g, append(current, part), depth+1)...)
+			added = true
+		}
+	}
+	if added && depth == 3 && g.IsCompact() {
+		return aliases
+	}
+	return append(aliases, depthNextStep(g, current, depth+1)...)
+}
+
+// depthNextStep maps the depth to the group combined at it, as the recursion did before the stages
+func depthNextStep(g *Generator, current []Part, depth int) []Alias {
+	switch depth {
+	case 2:
+		return depthCombine(g, current, g.Ops, depth)
+	case 3:
+		return depthCombine(g, current, g.Resources, depth)
+	case 4:
+		return depthCombine(g, current, g.Args, depth)
+	case 5:
+		return depthCombine(g, current, g.PosArgs, depth)
+	default:
+		return depthCombine(g, current, []Part{}, depth)
+	}
+}
+
+func TestStagesMatchDepthRecursion(t *testing.T) {
+	compact := Default()
+	compact.Resources = FilterResources(compact.Resources, []string{"po"})
+	compact.Compact = true
+	compact.PruneDangling()
+	for name, g := range map[string]Generator{"default": Default(), "compact": compact} {
+		var want []Alias
+		for _, cmd := range g.Commands {
+			want = append(want, depthCombine(&g, []Part{cmd}, g.GlobalOps, 1)...)
+			for _, extra := range g.Convenience {
+				want = append(want, g.NewAlias([]Part{cmd, extra}))
+			}
+		}
+		if got := g.Generate(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: the stages generate %d aliases, the depth recursion %d", name, len(got), len(want))
+		}
+	}
+}
+
+func TestDefaultGolden(t *testing.T) {
+	g := Default()
+	var got strings.Builder
+	for _, alias := range g.Generate() {
+		fmt.Fprintf(&got, "%s\t%s\n", alias.Name, alias.Command)
+	}
+	path := filepath.Join("testdata", "default.golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		gotLines, wantLines := strings.Split(got.String(), "\n"), strings.Split(string(want), "\n")
+		for i := 0; i < min(len(gotLines), len(wantLines)); i++ {
+			if gotLines[i] != wantLines[i] {
+				t.Fatalf("line %d is %q, want %q (go test -update rewrites %s)", i+1, gotLines[i], wantLines[i], path)
+			}
+		}
+		t.Fatalf("got %d lines, want %d (go test -update rewrites %s)", len(gotLines), len(wantLines), path)
+	}
+}
+
+func TestAddGroup(t *testing.T) {
+	g := testGenerator(Part{Alias: "po", Full: "pods"})
+	if err := g.AddGroup(StageArgs, []Part{{Alias: "w", Full: "--watch", AllowWhenOneOf: []string{"g"}}}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"kgpow", "kgpo", "kgw", "kg", "kdpo", "kd", "krmpo", "krm", "kpo", "k"}
+	if got := names(g.Generate()); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, stage := range []int{-1, len(g.Stages())} {
+		if err := g.AddGroup(stage, []Part{{Alias: "x", Full: "x"}}); err == nil {
+			t.Errorf("adding a group at stage %d should fail", stage)
+		}
+	}
+}
+
+func TestDedupeFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		tokens      string
+		want        string
+		wantDropped []string
+	}{
+		{"later output wins", "get pods -o=yaml -o=json", "get pods -o=json", []string{"-o=yaml"}},
+		{"short and long forms are one flag", "get pods --output=wide -o=yaml", "get pods -o=yaml", []string{"--output=wide"}},
+		{"baked namespaces", "--namespace=kube-system get pods -n=default", "get pods -n=default", []string{"--namespace=kube-system"}},
+		{"repeatable flags are kept", "create secret generic --from-literal=a=1 --from-literal=b=2 -l=a -l=b", "create secret generic --from-literal=a=1 --from-literal=b=2 -l=a -l=b", nil},
+		{"a flag left to fill in is kept", "--namespace=monitoring get pods --namespace", "--namespace=monitoring get pods --namespace", nil},
+		{"flags without values", "get pods --watch --watch", "get pods --watch --watch", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, dropped := dedupeFlags(strings.Fields(test.tokens))
+			if strings.Join(got, " ") != test.want || !slices.Equal(dropped, test.wantDropped) {
+				t.Errorf("got %v dropping %v, want %s dropping %v", got, dropped, test.want, test.wantDropped)
+			}
+		})
+	}
+}
+
+func TestConflictingArgsKeepLastOutput(t *testing.T) {
+	// A config op with a default output format combined with an explicit output arg
+	g := Generator{
+		Commands: []Part{{Alias: "k", Full: "kubectl"}},
+		Ops:      []Part{{Alias: "gy", Full: "get -o=yaml"}},
+		Args:     []Part{{Alias: "ojson", Full: "-o=json"}, {Alias: "l", Full: "-l=app=web -l=tier=frontend"}},
+	}
+	var warnings strings.Builder
+	g.Warnings = &warnings
+	commands := make(map[string]string)
+	for _, alias := range g.Generate() {
+		commands[alias.Name] = alias.Command
+	}
+	for name, want := range map[string]string{
+		"kgyojson": "kubectl get -o=json",
+		"kgyl":     "kubectl get -o=yaml -l=app=web -l=tier=frontend",
+	} {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+	if want := "warning: alias kgyojson overrides duplicate flag -o=yaml\n"; warnings.String() != want {
+		t.Errorf("got warnings %q, want %q", warnings.String(), want)
+	}
+}
+
+func TestSpaceFlags(t *testing.T) {
+	g := Default()
+	g.SpaceFlags = true
+	commands := make(map[string]string)
+	for _, alias := range g.Generate() {
+		commands[alias.Name] = alias.Command
+	}
+	tests := map[string]string{
+		"ksysgpo":   "kubectl --namespace kube-system get pods",
+		"kgpooyaml": "kubectl get pods -o yaml",
+		// A flag whose value is typed after the alias is already space separated
+		"kgpon": "kubectl get pods --namespace",
+		"kgpo":  "kubectl get pods",
+	}
+	for name, want := range tests {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+	for name, command := range commands {
+		for _, token := range strings.Fields(command) {
+			if strings.HasPrefix(token, "-") && strings.Contains(token, "=") {
+				t.Errorf("%s keeps %s joined with --flag-style space", name, token)
+			}
+		}
+	}
+}
+
+func TestDeleteWrapper(t *testing.T) {
+	g := Default()
+	g.DeleteWrapper = "confirm"
+	deletes := 0
+	for _, alias := range g.Generate() {
+		isDelete := alias.Operation == "delete"
+		if wrapped := strings.HasPrefix(alias.Command, "confirm kubectl "); wrapped != isDelete || alias.Function != isDelete {
+			t.Errorf("%s (%s) is wrapped: %v, a function: %v, want %v", alias.Name, alias.Command, wrapped, alias.Function, isDelete)
+		}
+		if isDelete {
+			deletes++
+		}
+	}
+	if deletes == 0 {
+		t.Fatal("no delete aliases were generated")
+	}
+	if got := commandsOf(g)["krmpon"]; got != "confirm kubectl delete pods --namespace" {
+		t.Errorf("krmpon expands to %q", got)
+	}
+}
+
+func TestChains(t *testing.T) {
+	pods := Part{Alias: "po", Full: "pods"}
+	tests := []struct {
+		name   string
+		chains []Chain
+		want   []Alias
+		err    bool
+	}{
+		{
+			name:   "allowed chain",
+			chains: []Chain{{Ops: []string{"g", "d"}}},
+			want: []Alias{{
+				Name: "kgd", Command: "kubectl get && kubectl describe", Function: true,
+				Steps: []string{"kubectl get", "kubectl describe"},
+			}},
+		},
+		{name: "single op", chains: []Chain{{Ops: []string{"g"}}}, err: true},
+		{name: "unknown op", chains: []Chain{{Ops: []string{"g", "lo"}}}, err: true},
+		{name: "repeated op", chains: []Chain{{Ops: []string{"g", "d", "g"}}}, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := testGenerator(pods)
+			g.Chains = test.chains
+			if err := g.ChainError(test.chains[0]); (err != nil) != test.err {
+				t.Errorf("got error %v, want one %v", err, test.err)
+			}
+
+			// Only the listed chains that pass the check are generated, never other pairs of ops
+			var chained []Alias
+			for _, alias := range g.Generate() {
+				if len(alias.Steps) > 0 {
+					chained = append(chained, alias)
+				}
+			}
+			if !reflect.DeepEqual(chained, test.want) {
+				t.Errorf("got %+v, want %+v", chained, test.want)
+			}
+		})
+	}
+}
+
+func TestChainStepsUseTheDeleteWrapper(t *testing.T) {
+	g := testGenerator()
+	g.DeleteWrapper = "confirm"
+	g.Chains = []Chain{{Ops: []string{"g", "rm"}}}
+	generated := g.Generate()
+	chain := generated[len(generated)-1]
+	if want := []string{"kubectl get", "confirm kubectl delete"}; !reflect.DeepEqual(chain.Steps, want) {
+		t.Errorf("got steps %q, want %q", chain.Steps, want)
+	}
+}
@@ -0,0 +1,343 @@
+package aliases
+
+import (
+	"slices"
+	"strings"
+)
+
+// DefaultRolloutTimeout is how long the built-in rollout status aliases wait by default
+const DefaultRolloutTimeout = "300s"
+
+// Default returns the generator for kubectl's built-in parts, giving the same aliases as kt aliases without flags
+func Default() Generator {
+	resources := Resources()
+	return Generator{
+		Commands: []Part{
+			{"k", "kubectl", nil, nil, "", ""},
+		},
+		GlobalOps: GlobalOps(),
+		Ops:       Operations(DefaultRolloutTimeout),
+		Resources: resources,
+		Args:      Arguments(),
+		PosArgs:   PositionalArgs(ResourceTypes(resources)),
+	}
+}
+
+// Tools maps each tool with built-in aliases, besides kubectl, to its own alias tree
+var Tools = map[string]func() Generator{
+	"helm":     HelmAliases,
+	"istioctl": IstioctlAliases,
+}
+
+// GlobalOps returns the flags combined right after the command, before the operation
+func GlobalOps() []Part {
+	return []Part{
+		NamespaceShortcut("sys", "kube-system"),
+	}
+}
+
+// NamespaceShortcut returns the global op scoping the command to the namespace. Every rule naming one namespace
+// shortcut applies to the others, so parts like nodes that can't be scoped only have to list sys
+func NamespaceShortcut(alias, namespace string) Part {
+	return Part{Alias: alias, Full: "--namespace=" + namespace}
+}
+
+// ClusterScoped returns the IncompatibleWith rule of cluster-scoped resources, which can't be given a namespace by a
+// namespace shortcut or the n positional. kubectl ignores --all-namespaces for them, so all is left out and the
+// delete --all variants sharing its alias still apply
+func ClusterScoped() []string {
+	return []string{"sys", "n"}
+}
+
+// Operations returns the kubectl operations, with rollout status waiting up to rolloutTimeout when it's set
+func Operations(rolloutTimeout string) []Part {
+	rolloutStatus := "rollout status"
+	if rolloutTimeout != "" {
+		rolloutStatus += " --timeout=" + rolloutTimeout
+	}
+
+	return []Part{
+		{"a", "apply --recursive -f", nil, nil, "", ""},
+		{"ak", "apply -k", nil, []string{"sys"}, "", ""},
+		{"k", "kustomize", nil, []string{"sys"}, "", ""},
+		{"ex", "exec -i -t", nil, nil, "", ""},
+		{"at", "attach -i -t", nil, nil, "", ""},
+		{"lo", "logs -f", nil, nil, "", ""},
+		{"lop", "logs -f -p", nil, nil, "", ""},
+		{"p", "proxy", nil, []string{"sys"}, "", ""},
+		{"pf", "port-forward", nil, []string{"sys"}, "", ""},
+		{"g", "get", nil, nil, "", ""},
+		{"d", "describe", nil, []string{"sys"}, "", ""},
+		{"rm", "delete", nil, []string{"sys"}, "", ""},
+		{"cr", "create", nil, nil, "", ""},
+		// replace needs the manifest, so like apply the op takes the file and doesn't combine with f
+		{"rep", "replace -f", nil, nil, "", ""},
+		{"rst", rolloutStatus, nil, nil, "", ""},
+		{"run", "run --rm --restart=Never --image-pull-policy=IfNotPresent -i -t", nil, nil, "", ""},
+		// create token takes the name of a service account rather than a resource type, so the resource is part of
+		// the alias and no resource is combined with it
+		{"toksa", "create token", nil, nil, "", ""},
+		// certificate approve/deny take the CSR name directly, so they never combine with a resource
+		{"ca", "certificate approve", nil, []string{"sys"}, "", ""},
+		{"cd", "certificate deny", nil, []string{"sys"}, "", ""},
+	}
+}
+
+// Resources returns the built-in resources
+func Resources() []Part {
+	return []Part{
+		// base k8s
+		{"po", "pods", []string{"g", "d", "rm"}, nil, "core", ""},
+		{"dep", "deployment", []string{"g", "d", "rm", "cr", "rst"}, nil, "core", "deployments"},
+		{"sts", "statefulset", []string{"g", "d", "rm", "rst"}, nil, "core", "statefulsets"},
+		{"svc", "service", []string{"g", "d", "rm"}, nil, "core", "services"},
+		{"ing", "ingress", []string{"g", "d", "rm"}, nil, "core", "ingresses"},
+		{"job", "job", []string{"g", "d", "rm", "cr"}, nil, "core", "jobs"},
+		{"cm", "configmap", []string{"g", "d", "rm", "cr"}, nil, "core", "configmaps"},
+		{"sec", "secret", []string{"g", "d", "rm", "cr"}, nil, "core", "secrets"},
+		{"sa", "serviceaccounts", []string{"g", "d", "rm"}, nil, "core", ""},
+		{"hpa", "horizontalpodautoscalers.v2.autoscaling", []string{"g", "d", "rm"}, nil, "core", "horizontalpodautoscalers"},
+		{"no", "nodes", []string{"g", "d"}, ClusterScoped(), "core", ""},
+		{"ns", "namespace", []string{"g", "d", "cr"}, ClusterScoped(), "core", "namespaces"},
+		{"csr", "certificatesigningrequests", []string{"g", "d", "rm"}, ClusterScoped(), "core", ""},
+		// istio
+		{"vs", "virtualservices", []string{"g", "d", "rm"}, nil, "istio", ""},
+	}
+}
+
+// DeprecatedResources returns resources that only exist on older clusters
+func DeprecatedResources() []Part {
+	return []Part{
+		{"psp", "podsecuritypolicies", []string{"g", "d"}, ClusterScoped(), "deprecated", ""},
+		{"ep", "endpoints", []string{"g", "d"}, nil, "deprecated", ""},
+	}
+}
+
+// AdvancedResources returns low-level resources that are left out by default.
+// Aliases are chosen so they can't be confused with the cr operation or with ep + sl
+func AdvancedResources() []Part {
+	return []Part{
+		{"cr2", "controllerrevisions", []string{"g", "d"}, nil, "advanced", ""},
+		{"es", "endpointslices", []string{"g", "d"}, nil, "advanced", ""},
+		{"lease", "leases", []string{"g", "d"}, nil, "advanced", ""},
+	}
+}
+
+// AuthResources returns review resources, which are only ever created from a manifest to read back the review.
+// kubectl create has no subcommand for them and only prints the created object's name, so they post the manifest
+// to the resource's endpoint with --raw, which prints the response
+func AuthResources() []Part {
+	return []Part{
+		{"tr", "--raw=/apis/authentication.k8s.io/v1/tokenreviews -f", []string{"cr"}, ClusterScoped(), "auth", "tokenreviews"},
+		{"sar", "--raw=/apis/authorization.k8s.io/v1/subjectaccessreviews -f", []string{"cr"}, ClusterScoped(), "auth", "subjectaccessreviews"},
+	}
+}
+
+// CertManagerResources returns the cert-manager CRDs, using fully-qualified names
+func CertManagerResources() []Part {
+	return []Part{
+		{"cert", "certificates.cert-manager.io", []string{"g", "d", "rm"}, nil, "cert-manager", "certificates"},
+		{"iss", "issuers.cert-manager.io", []string{"g", "d", "rm"}, nil, "cert-manager", "issuers"},
+		{"ciss", "clusterissuers.cert-manager.io", []string{"g", "d", "rm"}, ClusterScoped(), "cert-manager", "clusterissuers"},
+	}
+}
+
+// VPAResources returns the VerticalPodAutoscaler CRD of the Kubernetes autoscaler
+func VPAResources() []Part {
+	return []Part{
+		{"vpa", "verticalpodautoscalers.autoscaling.k8s.io", []string{"g", "d", "rm"}, nil, "vpa", "verticalpodautoscalers"},
+	}
+}
+
+// apiGroups maps the built-in resources outside the core group to their API group
+var apiGroups = map[string]string{
+	"deployment":                 "apps",
+	"statefulset":                "apps",
+	"controllerrevisions":        "apps",
+	"ingress":                    "networking.k8s.io",
+	"job":                        "batch",
+	"virtualservices":            "networking.istio.io",
+	"podsecuritypolicies":        "policy",
+	"endpointslices":             "discovery.k8s.io",
+	"leases":                     "coordination.k8s.io",
+	"certificatesigningrequests": "certificates.k8s.io",
+}
+
+// FilterCategories keeps the resources in one of the included categories, or every category when none are
+// included, and drops the resources in an excluded category
+func FilterCategories(resources []Part, include, exclude []string) []Part {
+	var kept []Part
+	for _, resource := range resources {
+		if len(include) > 0 && !slices.Contains(include, resource.Category) {
+			continue
+		}
+		if slices.Contains(exclude, resource.Category) {
+			continue
+		}
+		kept = append(kept, resource)
+	}
+	return kept
+}
+
+// FilterResources keeps the resources whose alias is one of names, in their original order
+func FilterResources(resources []Part, names []string) []Part {
+	var kept []Part
+	for _, resource := range resources {
+		if slices.Contains(names, resource.Alias) {
+			kept = append(kept, resource)
+		}
+	}
+	return kept
+}
+
+// QualifyResources returns a copy of resources with each name qualified by its API group. Names that are
+// already qualified or in the core group are left alone. kubectl create only accepts bare names, so
+// qualified resources no longer combine with the cr operation
+func QualifyResources(resources []Part) []Part {
+	var qualified []Part
+	for _, resource := range resources {
+		group, ok := apiGroups[resource.Full]
+		if ok && !strings.Contains(resource.Full, ".") {
+			resource.Full += "." + group
+			var allowed []string
+			for _, op := range resource.AllowWhenOneOf {
+				if op != "cr" {
+					allowed = append(allowed, op)
+				}
+			}
+			resource.AllowWhenOneOf = allowed
+		}
+		qualified = append(qualified, resource)
+	}
+	return qualified
+}
+
+// ForceOperations returns the operations that bypass kubectl's safety checks, such as replace --force deleting
+// and recreating the object
+func ForceOperations() []Part {
+	return []Part{
+		{"repforce", "replace --force -f", nil, nil, "", ""},
+	}
+}
+
+// RBACAliases returns convenience aliases for auth can-i, which takes a verb before the resource
+// and so can't be built from the operation and resource groups
+func RBACAliases() []Part {
+	return []Part{
+		{"cani", "auth can-i", nil, nil, "", ""},
+		{"canil", "auth can-i --list", nil, nil, "", ""},
+		{"canias", "auth can-i --as", nil, nil, "", ""},
+	}
+}
+
+// ExploreAliases returns convenience aliases for api-resources, which lists resource types rather than
+// taking one
+func ExploreAliases() []Part {
+	return []Part{
+		{"ar", "api-resources", nil, nil, "", ""},
+		{"aro", "api-resources -o=wide", nil, nil, "", ""},
+		{"arn", "api-resources --namespaced=true", nil, nil, "", ""},
+	}
+}
+
+// Categories returns the categories the resources are tagged with, each once in the order they first appear
+func Categories(resources []Part) []string {
+	var categories []string
+	for _, resource := range resources {
+		if resource.Category != "" && !slices.Contains(categories, resource.Category) {
+			categories = append(categories, resource.Category)
+		}
+	}
+	return categories
+}
+
+// ResourceTypes returns the aliases of the resources
+func ResourceTypes(resources []Part) []string {
+	var resourceTypes []string
+	for _, resource := range resources {
+		resourceTypes = append(resourceTypes, resource.Alias)
+	}
+	return resourceTypes
+}
+
+// Arguments returns the built-in flags combined after the resource
+func Arguments() []Part {
+	return []Part{
+		{"oyaml", "-o=yaml", []string{"g"}, []string{"owide", "ojson", "sl"}, "", ""},
+		{"owide", "-o=wide", []string{"g"}, []string{"oyaml", "ojson"}, "", ""},
+		{"ojson", "-o=json", []string{"g"}, []string{"owide", "oyaml", "sl"}, "", ""},
+		{"all", "--all-namespaces", []string{"g", "d"}, []string{"rm", "f", "no", "sys"}, "", ""},
+		{"sl", "--show-labels", []string{"g"}, []string{"oyaml", "ojson"}, "", ""},
+		{"all", "--all", []string{"rm"}, nil, "", ""},
+		{"w", "--watch", []string{"g"}, []string{"oyaml", "ojson", "owide"}, "", ""},
+		{"wevents", "--watch --output-watch-events", []string{"g"}, []string{"oyaml", "ojson", "owide", "w"}, "", ""},
+	}
+}
+
+// PositionalArgs returns the flags that take a value and so come last, incompatible with resourceTypes where
+// they don't apply
+func PositionalArgs(resourceTypes []string) []Part {
+	resourceTypes = append(resourceTypes, []string{"all", "l", "sys"}...)
+	return []Part{
+		{"f", "--recursive -f", []string{"g", "d", "rm"}, resourceTypes, "", ""},
+		{"l", "-l", []string{"g", "d", "rm"}, []string{"f", "all"}, "", ""},
+		{"n", "--namespace", []string{"g", "d", "rm", "lo", "ex", "at", "pf", "toksa", "cr", "rst"}, []string{"ns", "no", "sys", "all"}, "", ""},
+	}
+}
+
+// HelmAliases returns the alias tree for helm, e.g. hls for helm list and hupf for helm upgrade -f
+func HelmAliases() Generator {
+	return Generator{
+		Commands: []Part{
+			{"h", "helm", nil, nil, "", ""},
+		},
+		Ops: []Part{
+			{"ls", "list", nil, nil, "", ""},
+			{"st", "status", nil, nil, "", ""},
+			{"hi", "history", nil, nil, "", ""},
+			{"in", "install", nil, nil, "", ""},
+			{"up", "upgrade", nil, nil, "", ""},
+			{"un", "uninstall", nil, nil, "", ""},
+			{"rb", "rollback", nil, nil, "", ""},
+			{"gv", "get values", nil, nil, "", ""},
+			{"tpl", "template", nil, nil, "", ""},
+		},
+		Args: []Part{
+			{"all", "--all-namespaces", []string{"ls"}, []string{"n"}, "", ""},
+			{"dry", "--dry-run", []string{"in", "up", "un", "rb"}, nil, "", ""},
+			{"w", "--wait", []string{"in", "up", "rb"}, nil, "", ""},
+		},
+		PosArgs: []Part{
+			{"f", "-f", []string{"in", "up", "tpl"}, nil, "", ""},
+			{"n", "--namespace", []string{"ls", "st", "hi", "in", "up", "un", "rb", "gv"}, nil, "", ""},
+		},
+	}
+}
+
+// IstioctlAliases returns the alias tree for istioctl, e.g. icps for istioctl proxy-status
+func IstioctlAliases() Generator {
+	proxyConfig := []string{"pcc", "pcl", "pcr", "pce", "pcs"}
+	return Generator{
+		Commands: []Part{
+			{"ic", "istioctl", nil, nil, "", ""},
+		},
+		Ops: []Part{
+			{"an", "analyze", nil, nil, "", ""},
+			{"ps", "proxy-status", nil, nil, "", ""},
+			{"pcc", "proxy-config cluster", nil, nil, "", ""},
+			{"pcl", "proxy-config listener", nil, nil, "", ""},
+			{"pcr", "proxy-config route", nil, nil, "", ""},
+			{"pce", "proxy-config endpoint", nil, nil, "", ""},
+			{"pcs", "proxy-config secret", nil, nil, "", ""},
+			{"ver", "version", nil, nil, "", ""},
+		},
+		Args: []Part{
+			{"all", "--all-namespaces", []string{"an"}, []string{"n"}, "", ""},
+			{"oyaml", "-o=yaml", proxyConfig, nil, "", ""},
+			{"ojson", "-o=json", proxyConfig, nil, "", ""},
+		},
+		PosArgs: []Part{
+			{"n", "--namespace", append([]string{"an", "ps"}, proxyConfig...), nil, "", ""},
+		},
+	}
+}
@@ -0,0 +1,391 @@
+package aliases
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// commandsOf returns the generated aliases by name
+func commandsOf(g Generator) map[string]string {
+	commands := make(map[string]string)
+	for _, alias := range g.Generate() {
+		commands[alias.Name] = alias.Command
+	}
+	return commands
+}
+
+func TestTokenOnlyCombinesWithServiceAccounts(t *testing.T) {
+	g := Default()
+	var tokens []string
+	for _, alias := range g.Generate() {
+		if alias.Operation == "create token" {
+			tokens = append(tokens, alias.Name)
+		}
+	}
+	slices.Sort(tokens)
+	if want := []string{"ksystoksa", "ktoksa", "ktoksan"}; !slices.Equal(tokens, want) {
+		t.Errorf("create token aliases are %v, want %v", tokens, want)
+	}
+
+	commands := commandsOf(g)
+	tests := map[string]string{
+		"ktoksa":  "kubectl create token",
+		"ktoksan": "kubectl create token --namespace",
+		"kgsa":    "kubectl get serviceaccounts",
+	}
+	for name, want := range tests {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+}
+
+// optInGenerator returns the kubectl generator with every opt-in resource group and convenience alias added
+func optInGenerator() Generator {
+	g := Default()
+	for _, group := range [][]Part{DeprecatedResources(), AdvancedResources(), AuthResources(), CertManagerResources(), VPAResources()} {
+		g.Resources = append(g.Resources, group...)
+	}
+	g.Ops = append(g.Ops, ForceOperations()...)
+	g.PosArgs = PositionalArgs(ResourceTypes(g.Resources))
+	g.Convenience = append(RBACAliases(), ExploreAliases()...)
+	return g
+}
+
+func TestAuthResourcesOnlyCombineWithCreate(t *testing.T) {
+	g := Default()
+	g.Resources = append(g.Resources, AuthResources()...)
+	reviews := make(map[string]struct{})
+	for _, resource := range AuthResources() {
+		reviews[resource.Full] = struct{}{}
+	}
+
+	var got []string
+	for _, alias := range g.Generate() {
+		if _, ok := reviews[alias.Resource]; !ok {
+			continue
+		}
+		if alias.Operation != "create" {
+			t.Errorf("%s combines a review with %q", alias.Name, alias.Operation)
+		}
+		got = append(got, alias.Name)
+	}
+	if want := []string{"kcrtr", "kcrsar"}; !slices.Equal(got, want) {
+		t.Errorf("review aliases are %v, want %v", got, want)
+	}
+}
+
+func TestBuiltInsHaveNoDuplicateCommands(t *testing.T) {
+	generators := map[string]Generator{"kubectl": Default(), "kubectl opt-in": optInGenerator()}
+	for name, build := range Tools {
+		generators[name] = build()
+	}
+	for name, g := range generators {
+		if duplicates := FindDuplicates(g.Generate()); len(duplicates) > 0 {
+			t.Errorf("%s has aliases expanding to the same command: %v", name, duplicates)
+		}
+		if collisions := FindCollisions(g.Generate()); len(collisions) > 0 {
+			t.Errorf("%s has aliases expanding to more than one command: %v", name, collisions)
+		}
+	}
+}
+
+// scopeFlags returns the flags giving the command a namespace. --all-namespaces isn't one, kubectl ignores it for
+// cluster-scoped resources
+func scopeFlags(command string) []string {
+	var flags []string
+	for _, token := range strings.Fields(command) {
+		if strings.HasPrefix(token, "--namespace") {
+			flags = append(flags, token)
+		}
+	}
+	return flags
+}
+
+func TestClusterScopedResourcesAreNeverNamespaced(t *testing.T) {
+	clusterScoped := map[string]bool{
+		"nodes":                          true,
+		"certificatesigningrequests":     true,
+		"clusterissuers.cert-manager.io": true,
+	}
+	g := optInGenerator()
+	for _, alias := range g.Generate() {
+		if clusterScoped[alias.Resource] {
+			if flags := scopeFlags(alias.Command); len(flags) > 0 {
+				t.Errorf("%s scopes cluster-scoped %s with %v", alias.Name, alias.Resource, flags)
+			}
+		}
+	}
+}
+
+func TestCertificateApprovalTakesNoResource(t *testing.T) {
+	g := Default()
+	var got []string
+	for _, alias := range g.Generate() {
+		if strings.HasPrefix(alias.Operation, "certificate ") {
+			if alias.Resource != "" {
+				t.Errorf("%s combines %s with %s", alias.Name, alias.Operation, alias.Resource)
+			}
+			got = append(got, alias.Name)
+		}
+	}
+	if want := []string{"kca", "kcd"}; !slices.Equal(got, want) {
+		t.Errorf("certificate aliases are %v, want %v", got, want)
+	}
+}
+
+func TestCertManagerAliases(t *testing.T) {
+	g := Default()
+	g.Resources = append(g.Resources, CertManagerResources()...)
+	commands := commandsOf(g)
+	tests := map[string]string{
+		"kgcert":     "kubectl get certificates.cert-manager.io",
+		"kgissn":     "kubectl get issuers.cert-manager.io --namespace",
+		"kgissall":   "kubectl get issuers.cert-manager.io --all-namespaces",
+		"kgciss":     "kubectl get clusterissuers.cert-manager.io",
+		"krmcissall": "kubectl delete clusterissuers.cert-manager.io --all",
+		"kgcissn":    "",
+		"ksysgciss":  "",
+		"kgcsr":      "kubectl get certificatesigningrequests",
+		"kgcm":       "kubectl get configmap",
+		"krmcertall": "kubectl delete certificates.cert-manager.io --all",
+	}
+	for name, want := range tests {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCreateOnlyCombinesWithCreatableResources(t *testing.T) {
+	g := Default()
+	var resources []string
+	for _, alias := range g.Generate() {
+		if alias.Operation != "create" {
+			continue
+		}
+		// create takes a name and flags, so read-only output args make no sense with it
+		if alias.Argument != "" {
+			t.Errorf("%s combines create with %s", alias.Name, alias.Argument)
+		}
+		if alias.Resource != "" && !slices.Contains(resources, alias.Resource) {
+			resources = append(resources, alias.Resource)
+		}
+	}
+	if want := []string{"deployment", "job", "configmap", "secret", "namespace"}; !slices.Equal(resources, want) {
+		t.Errorf("create is combined with %v, want %v", resources, want)
+	}
+
+	commands := commandsOf(g)
+	for name, want := range map[string]string{
+		"kcrdep":  "kubectl create deployment",
+		"kcrcmn":  "kubectl create configmap --namespace",
+		"kcrns":   "kubectl create namespace",
+		"kcrpo":   "",
+		"kcrnsn":  "",
+		"kcrdepw": "",
+	} {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAdvancedResourcesDontCollide(t *testing.T) {
+	g := Default()
+	g.Resources = append(g.Resources, AdvancedResources()...)
+	g.PosArgs = PositionalArgs(ResourceTypes(g.Resources))
+	if collisions := FindCollisions(g.Generate()); len(collisions) > 0 {
+		t.Errorf("advanced resources collide: %v", collisions)
+	}
+
+	// Adding them only adds aliases, every default alias keeps its command
+	advanced := commandsOf(g)
+	for name, command := range commandsOf(Default()) {
+		if got, ok := advanced[name]; !ok || got != command {
+			t.Errorf("with the advanced resources %s expands to %q, want %q", name, got, command)
+		}
+	}
+	for _, want := range []string{"kgcr2", "kdcr2", "kges", "kglease"} {
+		if _, ok := advanced[want]; !ok {
+			t.Errorf("%s isn't generated", want)
+		}
+	}
+	for _, name := range []string{"kgcr2", "kges", "kglease"} {
+		if _, ok := commandsOf(Default())[name]; ok {
+			t.Errorf("%s is generated without the advanced resources", name)
+		}
+	}
+}
+
+func TestAutoscalerAliases(t *testing.T) {
+	g := Default()
+	g.Resources = append(g.Resources, VPAResources()...)
+	g.PosArgs = PositionalArgs(ResourceTypes(g.Resources))
+	if collisions := FindCollisions(g.Generate()); len(collisions) > 0 {
+		t.Errorf("autoscaler aliases collide: %v", collisions)
+	}
+
+	commands := commandsOf(g)
+	tests := map[string]string{
+		"kghpa":   "kubectl get horizontalpodautoscalers.v2.autoscaling",
+		"kdhpa":   "kubectl describe horizontalpodautoscalers.v2.autoscaling",
+		"krmhpan": "kubectl delete horizontalpodautoscalers.v2.autoscaling --namespace",
+		"kgvpa":   "kubectl get verticalpodautoscalers.autoscaling.k8s.io",
+		"kgvpan":  "kubectl get verticalpodautoscalers.autoscaling.k8s.io --namespace",
+		"krmvpa":  "kubectl delete verticalpodautoscalers.autoscaling.k8s.io",
+	}
+	for name, want := range tests {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+	// Only get, describe and delete apply to them
+	for _, alias := range g.Generate() {
+		if strings.Contains(alias.Resource, "podautoscalers") && !slices.Contains([]string{"get", "describe", "delete"}, alias.Operation) {
+			t.Errorf("%s combines %s with %q", alias.Name, alias.Resource, alias.Operation)
+		}
+	}
+	if _, ok := commandsOf(Default())["kgvpa"]; ok {
+		t.Errorf("kgvpa is generated without the VPA resources")
+	}
+}
+
+func TestAttachTakesThePodLikeExec(t *testing.T) {
+	g := optInGenerator()
+	var got []string
+	for _, alias := range g.Generate() {
+		if alias.Operation != "attach -i -t" && alias.Operation != "exec -i -t" {
+			continue
+		}
+		// The pod is given by name, so neither is combined with a resource type or an output argument
+		if alias.Resource != "" || alias.Argument != "" {
+			t.Errorf("%s combines %s with resource %q and argument %q", alias.Name, alias.Operation, alias.Resource, alias.Argument)
+		}
+		got = append(got, alias.Name)
+	}
+	slices.Sort(got)
+	if want := []string{"kat", "katn", "kex", "kexn", "ksysat", "ksysex"}; !slices.Equal(got, want) {
+		t.Errorf("attach and exec aliases are %v, want %v", got, want)
+	}
+	if got := commandsOf(g)["katn"]; got != "kubectl attach -i -t --namespace" {
+		t.Errorf("katn expands to %q", got)
+	}
+}
+
+func TestQualifyResources(t *testing.T) {
+	resources := []Part{
+		{"po", "pods", []string{"g", "d"}, nil, "core", ""},
+		{"dep", "deployment", []string{"g", "cr", "rst"}, nil, "core", ""},
+		{"ing", "ingress", []string{"g"}, nil, "core", ""},
+		{"cert", "certificates.cert-manager.io", []string{"g"}, nil, "cert-manager", ""},
+	}
+	got := QualifyResources(resources)
+	want := []Part{
+		{"po", "pods", []string{"g", "d"}, nil, "core", ""},
+		{"dep", "deployment.apps", []string{"g", "rst"}, nil, "core", ""},
+		{"ing", "ingress.networking.k8s.io", []string{"g"}, nil, "core", ""},
+		{"cert", "certificates.cert-manager.io", []string{"g"}, nil, "cert-manager", ""},
+	}
+	if !slices.EqualFunc(got, want, func(a, b Part) bool {
+		return a.Alias == b.Alias && a.Full == b.Full && slices.Equal(a.AllowWhenOneOf, b.AllowWhenOneOf)
+	}) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if resources[1].Full != "deployment" || !slices.Contains(resources[1].AllowWhenOneOf, "cr") {
+		t.Errorf("qualifying changed the original resources: %v", resources[1])
+	}
+
+	// Every built-in resource outside the core group is qualified, so none is left ambiguous
+	for _, resource := range QualifyResources(Resources()) {
+		if _, ok := apiGroups[resource.Full]; ok {
+			t.Errorf("%s is left unqualified", resource.Full)
+		}
+	}
+}
+
+func TestClusterScopedResourcesKeepDeleteAll(t *testing.T) {
+	g := optInGenerator()
+	commands := commandsOf(g)
+	tests := map[string]string{
+		"krmcsrall":  "kubectl delete certificatesigningrequests --all",
+		"krmcissall": "kubectl delete clusterissuers.cert-manager.io --all",
+		"kgcsrn":     "",
+		"ksysgcsr":   "",
+	}
+	for name, want := range tests {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWatchEventsOnlyOnGet(t *testing.T) {
+	g := Default()
+	found := false
+	for _, alias := range g.Generate() {
+		if !strings.Contains(alias.Command, "--output-watch-events") {
+			continue
+		}
+		found = true
+		if alias.Operation != "get" {
+			t.Errorf("%s watches events with %q", alias.Name, alias.Operation)
+		}
+		if strings.Contains(alias.Command, "-o=") || strings.Count(alias.Command, "--watch") != 1 {
+			t.Errorf("%s combines the watch events with another output: %s", alias.Name, alias.Command)
+		}
+	}
+	if !found {
+		t.Fatal("no alias watches events")
+	}
+
+	commands := commandsOf(g)
+	if got, want := commands["kgpowevents"], "kubectl get pods --watch --output-watch-events"; got != want {
+		t.Errorf("kgpowevents expands to %q, want %q", got, want)
+	}
+	for _, name := range []string{"kgpooyamlwevents", "kgpoweventsoyaml", "kdpowevents"} {
+		if command, ok := commands[name]; ok {
+			t.Errorf("unexpected %s: %s", name, command)
+		}
+	}
+}
+
+func TestReplaceTakesTheFile(t *testing.T) {
+	g := optInGenerator()
+	commands := commandsOf(g)
+	tests := map[string]string{
+		"krep":        "kubectl replace -f",
+		"ksysrep":     "kubectl --namespace=kube-system replace -f",
+		"krepforce":   "kubectl replace --force -f",
+		"krepf":       "",
+		"krepforcef":  "",
+		"krepoyaml":   "",
+		"kreppo":      "",
+		"krepforcepo": "",
+	}
+	for name, want := range tests {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+	for name, command := range commands {
+		fields := strings.Fields(command)
+		files := len(slices.DeleteFunc(slices.Clone(fields), func(field string) bool { return field != "-f" }))
+		if slices.Contains(fields, "replace") && files != 1 {
+			t.Errorf("%s doesn't take exactly one file: %s", name, command)
+		}
+	}
+}
+
+func TestCategories(t *testing.T) {
+	resources := []Part{
+		{Alias: "po", Full: "pods", Category: "core"},
+		{Alias: "cert", Full: "certificate", Category: "cert-manager"},
+		{Alias: "svc", Full: "service", Category: "core"},
+		{Alias: "x", Full: "untagged"},
+	}
+	if got, want := Categories(resources), []string{"core", "cert-manager"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
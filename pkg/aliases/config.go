@@ -0,0 +1,322 @@
+package aliases
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// NamespacePattern matches valid Kubernetes namespace names, which also keeps them safe to use as file names
+var NamespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Config holds the parts a kt aliases --config file adds to, or replaces, the built-in ones
+type Config struct {
+	// Replace swaps the built-in parts of each group the file defines for the file's parts,
+	// instead of adding to them. Groups the file leaves out keep their built-in parts, while a group
+	// defined as an empty list replaces them with nothing. The groups are always marshalled, so a
+	// dumped config keeps the groups that resolved to no parts
+	Replace     bool   `yaml:"replace"`
+	Commands    []Part `yaml:"commands"`
+	GlobalOps   []Part `yaml:"globalOps"`
+	Ops         []Part `yaml:"ops"`
+	Resources   []Part `yaml:"resources"`
+	Args        []Part `yaml:"args"`
+	PosArgs     []Part `yaml:"posArgs"`
+	Convenience []Part `yaml:"convenience"`
+	// Deprecated lists the aliases of resources that are marked as deprecated in the output
+	Deprecated []string `yaml:"deprecated,omitempty"`
+	// Presets adds presets for --preset, replacing the built-in ones of the same name
+	Presets map[string]Preset `yaml:"presets,omitempty"`
+	// Chains lists the op chains generated with --combine-ops
+	Chains []Chain `yaml:"chains,omitempty"`
+	// Teams adds the bundles that can be selected with --team
+	Teams map[string]Team `yaml:"teams,omitempty"`
+}
+
+// Preset bundles several kt aliases flags under a single name. kt applies presets as flags, so FromConfig
+// ignores them
+type Preset struct {
+	Description string            `yaml:"description,omitempty"`
+	Flags       map[string]string `yaml:"flags"`
+}
+
+// Team bundles the namespace, resources and operations a team works with into a single alias set
+type Team struct {
+	// Namespace is baked into every alias in place of the namespace shortcuts
+	Namespace string `yaml:"namespace"`
+	// Resources lists the aliases of the team's resources, all of them when empty
+	Resources []string `yaml:"resources,omitempty"`
+	// Ops lists the aliases of the team's operations, all of them when empty
+	Ops []string `yaml:"ops,omitempty"`
+}
+
+// ParseConfig parses a YAML config, or a JSON one when format is json, rejecting unknown fields and parts
+// without an alias or expansion
+func ParseConfig(data []byte, format string) (Config, error) {
+	// JSON is parsed as the YAML it's a subset of, so both formats share the field names and the unknown field
+	// check, once it's known to be valid JSON rather than YAML that happens to parse
+	if format == "json" {
+		var parsed any
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return Config{}, fmt.Errorf("parsing as JSON: %w", err)
+		}
+	}
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("parsing: %w", err)
+	}
+
+	groups := map[string][]Part{
+		"commands":    config.Commands,
+		"globalOps":   config.GlobalOps,
+		"ops":         config.Ops,
+		"resources":   config.Resources,
+		"args":        config.Args,
+		"posArgs":     config.PosArgs,
+		"convenience": config.Convenience,
+	}
+	for name, parts := range groups {
+		for i, part := range parts {
+			if part.Alias == "" || part.Full == "" {
+				return Config{}, fmt.Errorf("%s[%d] needs both an alias and a full expansion", name, i)
+			}
+		}
+	}
+	return config, nil
+}
+
+// Merge combines the built-in parts of a group with the parts the config defines for it. A nil group is one the
+// config leaves out, while an empty one is defined without parts
+func (c Config) Merge(builtIn []Part, configured []Part) []Part {
+	if configured == nil {
+		return builtIn
+	}
+	if c.Replace {
+		return configured
+	}
+	return append(builtIn, configured...)
+}
+
+// Options selects the built-in parts FromConfig starts from and the filters it applies, one for each kt aliases
+// flag that shapes the parts
+type Options struct {
+	// Tools selects the tools aliases are generated for, kubectl or a key of Tools
+	Tools []string
+	// Prefix and Bin are the alias and expansion of the kubectl command
+	Prefix string
+	Bin    string
+	// Suffix is appended to every alias name
+	Suffix string
+	// RolloutTimeout is baked into the rollout status aliases
+	RolloutTimeout string
+	// Deprecated, Advanced, Auth, CertManager and VPA add the optional resource sets
+	Deprecated  bool
+	Advanced    bool
+	Auth        bool
+	CertManager bool
+	VPA         bool
+	// Include and Exclude limit the resources by category, and Only to the listed aliases
+	Include []string
+	Exclude []string
+	Only    []string
+	// QualifyGroups expands resources to their fully qualified name with the API group
+	QualifyGroups bool
+	// Team names the config team whose namespace, resources and operations the aliases are limited to
+	Team string
+	// NamespaceShortcuts holds alias=namespace pairs, each added as a global op like sys
+	NamespaceShortcuts []string
+	// DenyVerbs lists operations, by alias or kubectl verb, that no alias is generated for
+	DenyVerbs []string
+	// Force adds the --force operations, CombineOps the config's chains, and RBAC and Explore their convenience
+	// aliases
+	Force      bool
+	CombineOps bool
+	RBAC       bool
+	Explore    bool
+	// Compact, SpaceFlags, DeleteWrapper and Warnings set the generator fields of the same name
+	Compact       bool
+	SpaceFlags    bool
+	DeleteWrapper string
+	Warnings      io.Writer
+	// Normalize lowercases the part aliases and strips the characters that aren't valid in alias names
+	Normalize bool
+	// AddResources, when set, returns resources to add to the configured ones before they're filtered, given the
+	// aliases already taken by resources and args
+	AddResources func(resources []Part, taken map[string]struct{}) ([]Part, error)
+	// KeepResources, when set, narrows the resources once they're filtered and qualified
+	KeepResources func(resources []Part) []Part
+}
+
+// DefaultOptions returns the options kt aliases uses without flags
+func DefaultOptions() Options {
+	return Options{Tools: []string{"kubectl"}, Prefix: "k", Bin: "kubectl", RolloutTimeout: DefaultRolloutTimeout}
+}
+
+// FromConfig builds the generator kt aliases renders for the config and options. Like Default, the generator
+// isn't validated, which is left to Validate
+func FromConfig(config Config, opts Options) (Generator, error) {
+	resources := Resources()
+	deprecated := make(map[string]struct{})
+	if opts.Deprecated {
+		for _, resource := range DeprecatedResources() {
+			deprecated[resource.Alias] = struct{}{}
+			resources = append(resources, resource)
+		}
+	}
+	if opts.Advanced {
+		resources = append(resources, AdvancedResources()...)
+	}
+	if opts.Auth {
+		resources = append(resources, AuthResources()...)
+	}
+	if opts.CertManager {
+		resources = append(resources, CertManagerResources()...)
+	}
+	if opts.VPA {
+		resources = append(resources, VPAResources()...)
+	}
+	resources = config.Merge(resources, config.Resources)
+	for _, alias := range config.Deprecated {
+		deprecated[alias] = struct{}{}
+	}
+	args := config.Merge(Arguments(), config.Args)
+	if opts.AddResources != nil {
+		taken := make(map[string]struct{})
+		for _, group := range [][]Part{resources, args, PositionalArgs(nil)} {
+			for _, part := range group {
+				taken[part.Alias] = struct{}{}
+			}
+		}
+		added, err := opts.AddResources(resources, taken)
+		if err != nil {
+			return Generator{}, err
+		}
+		resources = append(resources, added...)
+	}
+	if len(opts.Include) > 0 || len(opts.Exclude) > 0 {
+		categories := Categories(resources)
+		for _, name := range append(slices.Clone(opts.Include), opts.Exclude...) {
+			if !slices.Contains(categories, name) {
+				return Generator{}, fmt.Errorf("unknown category %q, expected one of %s", name, strings.Join(categories, ", "))
+			}
+		}
+		resources = FilterCategories(resources, opts.Include, opts.Exclude)
+	}
+	if len(opts.Only) > 0 {
+		defined := ResourceTypes(resources)
+		for _, name := range opts.Only {
+			if !slices.Contains(defined, name) {
+				return Generator{}, fmt.Errorf("unknown resource %q", name)
+			}
+		}
+		resources = FilterResources(resources, opts.Only)
+	}
+	var team Team
+	if opts.Team != "" {
+		var ok bool
+		if team, ok = config.Teams[opts.Team]; !ok {
+			return Generator{}, fmt.Errorf("unknown team %q, expected one defined under teams in the config file", opts.Team)
+		}
+		if !NamespacePattern.MatchString(team.Namespace) {
+			return Generator{}, fmt.Errorf("team %s: invalid namespace %q", opts.Team, team.Namespace)
+		}
+		if len(team.Resources) > 0 {
+			defined := ResourceTypes(resources)
+			for _, name := range team.Resources {
+				if !slices.Contains(defined, name) {
+					return Generator{}, fmt.Errorf("team %s: unknown resource %q", opts.Team, name)
+				}
+			}
+			resources = FilterResources(resources, team.Resources)
+		}
+	}
+	if opts.QualifyGroups {
+		resources = QualifyResources(resources)
+	}
+	if opts.KeepResources != nil {
+		resources = opts.KeepResources(resources)
+	}
+
+	ops := Operations(opts.RolloutTimeout)
+	if opts.Force {
+		ops = append(ops, ForceOperations()...)
+	}
+
+	globalOps := config.Merge(GlobalOps(), config.GlobalOps)
+	for _, shortcut := range opts.NamespaceShortcuts {
+		alias, namespace, _ := strings.Cut(shortcut, "=")
+		globalOps = append(globalOps, NamespaceShortcut(alias, namespace))
+	}
+
+	g := Generator{
+		Commands:      config.Merge([]Part{{Alias: opts.Prefix, Full: opts.Bin}}, config.Commands),
+		GlobalOps:     globalOps,
+		Ops:           config.Merge(ops, config.Ops),
+		Resources:     resources,
+		Args:          args,
+		PosArgs:       config.Merge(PositionalArgs(ResourceTypes(resources)), config.PosArgs),
+		Deprecated:    deprecated,
+		Compact:       opts.Compact,
+		Warnings:      opts.Warnings,
+		SpaceFlags:    opts.SpaceFlags,
+		DeleteWrapper: opts.DeleteWrapper,
+		Suffix:        opts.Suffix,
+	}
+	if opts.CombineOps {
+		// Chains are checked against every op, as denying one later only skips the chains running it
+		for _, chain := range config.Chains {
+			if err := g.ChainError(chain); err != nil {
+				return Generator{}, err
+			}
+		}
+		g.Chains = config.Chains
+	}
+	if opts.RBAC {
+		g.Convenience = append(g.Convenience, RBACAliases()...)
+	}
+	if opts.Explore {
+		g.Convenience = append(g.Convenience, ExploreAliases()...)
+	}
+	g.Convenience = config.Merge(g.Convenience, config.Convenience)
+	// The tools are added before the filters, which apply to their parts as well
+	for _, name := range opts.Tools {
+		if build, ok := Tools[name]; ok {
+			tool := build()
+			tool.Warnings, tool.SpaceFlags, tool.Suffix = g.Warnings, g.SpaceFlags, g.Suffix
+			g.Tools = append(g.Tools, &tool)
+		}
+	}
+	if opts.Team != "" {
+		// The namespace is fixed, and a team with a single resource drops it from the alias names
+		g.ScopeToNamespace(team.Namespace)
+		g.Compact = true
+		for _, name := range team.Ops {
+			if !slices.ContainsFunc(g.Ops, func(op Part) bool { return op.Alias == name }) {
+				return Generator{}, fmt.Errorf("team %s: unknown operation %q", opts.Team, name)
+			}
+		}
+		if len(team.Ops) > 0 {
+			g.KeepOps(team.Ops)
+		}
+	}
+	if len(opts.DenyVerbs) > 0 {
+		g.DenyOps(opts.DenyVerbs)
+	} else if len(opts.Include) > 0 || len(opts.Exclude) > 0 || len(opts.Only) > 0 || opts.Team != "" {
+		g.PruneDangling()
+	}
+	if opts.Normalize {
+		g.Normalize()
+	}
+	if !slices.Contains(opts.Tools, "kubectl") {
+		g.Commands = nil
+	}
+	return g, nil
+}
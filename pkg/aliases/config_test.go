@@ -0,0 +1,111 @@
+package aliases
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigMerge(t *testing.T) {
+	builtIn := []Part{{Alias: "po", Full: "pods"}, {Alias: "svc", Full: "service"}}
+	configured := []Part{{Alias: "rs", Full: "replicasets"}}
+	tests := []struct {
+		name       string
+		replace    bool
+		configured []Part
+		want       []Part
+	}{
+		{"adds to the built-ins", false, configured, []Part{builtIn[0], builtIn[1], configured[0]}},
+		{"replaces the built-ins", true, configured, configured},
+		{"keeps the built-ins when the group is left out", false, nil, builtIn},
+		{"keeps the built-ins when a replacing config leaves the group out", true, nil, builtIn},
+		{"keeps the built-ins when an empty group is added", false, []Part{}, builtIn},
+		{"drops the built-ins when a replacing config empties the group", true, []Part{}, []Part{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{Replace: test.replace}
+			if got := config.Merge(builtIn, test.configured); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		wantErr string
+	}{
+		{name: "yaml", content: "resources:\n  - alias: rs\n    full: replicasets\nteams:\n  web:\n    namespace: web\n"},
+		{name: "json", content: `{"ops": [{"alias": "ed", "full": "edit"}]}`, format: "json"},
+		{name: "presets", content: "presets:\n  sre:\n    flags:\n      advanced: true\n"},
+		{name: "unknown field", content: "resource: []\n", wantErr: "field resource not found"},
+		{name: "part without an expansion", content: "args:\n  - alias: w\n", wantErr: "args[0] needs both an alias and a full expansion"},
+		{name: "invalid json", content: "ops: []\n", format: "json", wantErr: "parsing as JSON"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(test.content), test.format)
+			if test.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromConfigDefaults(t *testing.T) {
+	g, err := FromConfig(Config{}, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	want := Default()
+	if got := g.Generate(); !reflect.DeepEqual(got, want.Generate()) {
+		t.Errorf("FromConfig with the default options generates %d aliases, want the %d of Default", len(got), len(want.Generate()))
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	config := Config{
+		Resources: []Part{{Alias: "rs", Full: "replicasets", AllowWhenOneOf: []string{"g"}, Category: "core"}},
+		Teams:     map[string]Team{"web": {Namespace: "web", Resources: []string{"po", "rs"}, Ops: []string{"g", "lo"}}},
+	}
+	opts := DefaultOptions()
+	opts.Tools = append(opts.Tools, "helm")
+	opts.Team = "web"
+	g, err := FromConfig(config, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := commandsOf(g)
+	for name, want := range map[string]string{
+		"kgrs": "kubectl --namespace=web get replicasets",
+		"klo":  "kubectl --namespace=web logs -f",
+		"hls":  "helm --namespace=web list",
+	} {
+		if got := commands[name]; got != want {
+			t.Errorf("%s expands to %q, want %q", name, got, want)
+		}
+	}
+	if _, ok := commands["kdpo"]; ok {
+		t.Error("the team's aliases run describe, which it leaves out")
+	}
+
+	for name, opts := range map[string]Options{
+		"unknown category": {Include: []string{"nope"}},
+		"unknown resource": {Only: []string{"nope"}},
+		"unknown team":     {Team: "nope"},
+	} {
+		if _, err := FromConfig(config, opts); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package aliases
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Validate checks that every alias referenced by AllowWhenOneOf or IncompatibleWith is defined by some part
+func (g *Generator) Validate() error {
+	groups := [][]Part{g.Commands, g.GlobalOps, g.Ops, g.Resources, g.Args, g.PosArgs, g.Convenience}
+	defined := g.DefinedAliases()
+
+	var errs []error
+	for _, group := range groups {
+		for _, part := range group {
+			for _, alias := range part.AllowWhenOneOf {
+				if _, exists := defined[alias]; !exists {
+					errs = append(errs, fmt.Errorf("part %s: allowWhenOneOf references unknown alias %q", part.Alias, alias))
+				}
+			}
+			for _, alias := range part.IncompatibleWith {
+				if _, exists := defined[alias]; !exists {
+					errs = append(errs, fmt.Errorf("part %s: incompatibleWith references unknown alias %q", part.Alias, alias))
+				}
+			}
+		}
+	}
+	for _, tool := range g.Tools {
+		errs = append(errs, tool.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+// DefinedAliases returns the set of aliases defined by any part
+func (g *Generator) DefinedAliases() map[string]struct{} {
+	defined := make(map[string]struct{})
+	for _, group := range [][]Part{g.Commands, g.GlobalOps, g.Ops, g.Resources, g.Args, g.PosArgs, g.Convenience} {
+		for _, part := range group {
+			defined[part.Alias] = struct{}{}
+		}
+	}
+	return defined
+}
+
+// DenyOps removes the operations, of this tree and each tool's, whose alias or verb is in verbs, so no alias runs
+// them
+func (g *Generator) DenyOps(verbs []string) {
+	g.Ops = slices.DeleteFunc(g.Ops, func(op Part) bool {
+		fields := strings.Fields(op.Full)
+		return slices.Contains(verbs, op.Alias) || (len(fields) > 0 && slices.Contains(verbs, fields[0]))
+	})
+	g.PruneDangling()
+	for _, tool := range g.Tools {
+		tool.DenyOps(verbs)
+	}
+}
+
+// KeepOps removes the operations whose alias isn't in names, so aliases only run the listed ones
+func (g *Generator) KeepOps(names []string) {
+	g.Ops = slices.DeleteFunc(g.Ops, func(op Part) bool { return !slices.Contains(names, op.Alias) })
+	g.PruneDangling()
+}
+
+// ScopeToNamespace bakes the namespace into every command, of this tree and each tool's, and drops the parts that
+// would pick another: the global ops such as the namespace shortcuts, --all-namespaces and the n positional
+func (g *Generator) ScopeToNamespace(namespace string) {
+	for i := range g.Commands {
+		g.Commands[i].Full += " --namespace=" + namespace
+	}
+	g.GlobalOps = nil
+	g.Args = slices.DeleteFunc(g.Args, func(part Part) bool { return part.Full == "--all-namespaces" })
+	g.PosArgs = slices.DeleteFunc(g.PosArgs, func(part Part) bool { return part.Alias == "n" })
+	g.PruneDangling()
+	for _, tool := range g.Tools {
+		tool.ScopeToNamespace(namespace)
+	}
+}
+
+// PruneDangling drops the parts whose AllowWhenOneOf only names aliases no part defines any more, then strips
+// the remaining references to undefined aliases so the parts still validate
+func (g *Generator) PruneDangling() {
+	groups := []*[]Part{&g.Commands, &g.GlobalOps, &g.Ops, &g.Resources, &g.Args, &g.PosArgs, &g.Convenience}
+	for dropped := true; dropped; {
+		dropped = false
+		defined := g.DefinedAliases()
+		for _, group := range groups {
+			*group = slices.DeleteFunc(*group, func(part Part) bool {
+				if len(part.AllowWhenOneOf) == 0 {
+					return false
+				}
+				for _, alias := range part.AllowWhenOneOf {
+					if _, exists := defined[alias]; exists {
+						return false
+					}
+				}
+				dropped = true
+				return true
+			})
+		}
+	}
+
+	defined := g.DefinedAliases()
+	undefined := func(alias string) bool {
+		_, exists := defined[alias]
+		return !exists
+	}
+	for _, group := range groups {
+		for i := range *group {
+			part := &(*group)[i]
+			part.AllowWhenOneOf = slices.DeleteFunc(part.AllowWhenOneOf, undefined)
+			part.IncompatibleWith = slices.DeleteFunc(part.IncompatibleWith, undefined)
+		}
+	}
+}
+
+// FindCollisions returns the alias names that expand to more than one distinct command, with their commands
+func FindCollisions(aliases []Alias) map[string][]string {
+	commands := make(map[string][]string)
+	for _, alias := range aliases {
+		if !slices.Contains(commands[alias.Name], alias.Command) {
+			commands[alias.Name] = append(commands[alias.Name], alias.Command)
+		}
+	}
+
+	collisions := make(map[string][]string)
+	for name, expansions := range commands {
+		if len(expansions) > 1 {
+			collisions[name] = expansions
+		}
+	}
+	return collisions
+}
+
+// FindDuplicates returns the commands that more than one alias name expands to, with their names
+func FindDuplicates(aliases []Alias) map[string][]string {
+	names := make(map[string][]string)
+	for _, alias := range aliases {
+		if !slices.Contains(names[alias.Command], alias.Name) {
+			names[alias.Command] = append(names[alias.Command], alias.Name)
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for command, aliases := range names {
+		if len(aliases) > 1 {
+			duplicates[command] = aliases
+		}
+	}
+	return duplicates
+}
+
+// Dedup keeps only the first alias with each name, so a name is never defined twice and the
+// command it ends up with doesn't depend on which definition the shell reads last
+func Dedup(aliases []Alias) []Alias {
+	seen := make(map[string]struct{}, len(aliases))
+	return slices.DeleteFunc(aliases, func(alias Alias) bool {
+		if _, exists := seen[alias.Name]; exists {
+			return true
+		}
+		seen[alias.Name] = struct{}{}
+		return false
+	})
+}
+
+// Suggestion proposes an unused alias for a part that reuses the alias of an earlier part in its group
+type Suggestion struct {
+	Part  Part
+	Alias string
+}
+
+// SuggestFixes proposes an alias for every part that reuses the alias of an earlier part in its group, such as the
+// second all. Each proposal is one no part defines and no earlier proposal took
+func (g *Generator) SuggestFixes() []Suggestion {
+	taken := g.DefinedAliases()
+	var suggestions []Suggestion
+	for _, group := range [][]Part{g.Commands, g.GlobalOps, g.Ops, g.Resources, g.Args, g.PosArgs, g.Convenience} {
+		seen := make(map[string]struct{})
+		for _, part := range group {
+			if _, reused := seen[part.Alias]; !reused {
+				seen[part.Alias] = struct{}{}
+				continue
+			}
+			if alias, found := unusedAlias(part.Alias, taken); found {
+				taken[alias] = struct{}{}
+				suggestions = append(suggestions, Suggestion{Part: part, Alias: alias})
+			}
+		}
+	}
+	return suggestions
+}
+
+// unusedAlias returns the first alias not in taken that swaps the last letter of alias for another one, keeping
+// it as short as it is, or else that adds a letter to the end of it
+func unusedAlias(alias string, taken map[string]struct{}) (string, bool) {
+	var stems []string
+	if alias != "" {
+		stems = append(stems, alias[:len(alias)-1])
+	}
+	for _, stem := range append(stems, alias) {
+		for letter := 'a'; letter <= 'z'; letter++ {
+			candidate := stem + string(letter)
+			if _, used := taken[candidate]; !used {
+				return candidate, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Normalize lowercases every part alias, and the references to it, and strips characters that
+// aren't letters, digits or underscores so the generated names are valid shell aliases
+func (g *Generator) Normalize() {
+	for _, group := range [][]Part{g.Commands, g.GlobalOps, g.Ops, g.Resources, g.Args, g.PosArgs, g.Convenience} {
+		for i := range group {
+			group[i].Alias = NormalizeAlias(group[i].Alias)
+			group[i].AllowWhenOneOf = normalizeAliasList(group[i].AllowWhenOneOf)
+			group[i].IncompatibleWith = normalizeAliasList(group[i].IncompatibleWith)
+		}
+	}
+
+	deprecated := make(map[string]struct{}, len(g.Deprecated))
+	for alias := range g.Deprecated {
+		deprecated[NormalizeAlias(alias)] = struct{}{}
+	}
+	g.Deprecated = deprecated
+}
+
+// NormalizeAlias lowercases alias and drops any character other than a-z, 0-9 and _
+func NormalizeAlias(alias string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(alias))
+}
+
+// normalizeAliasList returns a copy of aliases with each one normalized
+func normalizeAliasList(aliases []string) []string {
+	var normalized []string
+	for _, alias := range aliases {
+		normalized = append(normalized, NormalizeAlias(alias))
+	}
+	return normalized
+}
+
+// Reasons a part can be rejected from a combination
+const (
+	RejectIncompatibleWith = "incompatible-with"
+	RejectAllowWhenOneOf   = "allow-when-one-of"
+)
+
+// Excludes reports whether part lists other in IncompatibleWith, by its alias or through another namespace shortcut
+func (g *Generator) Excludes(part, other Part) bool {
+	return g.refersTo(part.IncompatibleWith, other)
+}
+
+// refersTo reports whether one of the aliases names the part. Naming any namespace shortcut names all of them,
+// so a rule written against sys applies to every shortcut added next to it
+func (g *Generator) refersTo(aliases []string, part Part) bool {
+	shortcut := g.isNamespaceShortcut(part)
+	for _, alias := range aliases {
+		if alias == part.Alias {
+			return true
+		}
+		if shortcut && g.isNamespaceShortcutAlias(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidCombination checks if adding a new part to the current combination is valid
+func (g *Generator) IsValidCombination(current []Part, newPart Part) bool {
+	return g.RejectionReason(current, newPart) == ""
+}
+
+// RejectionReason returns why adding a new part to the current combination is invalid, or "" if it's valid
+func (g *Generator) RejectionReason(current []Part, newPart Part) string {
+	// Incompatibility goes both ways, whichever of the two parts declares it
+	for _, part := range current {
+		if g.Excludes(part, newPart) || g.Excludes(newPart, part) {
+			return RejectIncompatibleWith
+		}
+	}
+
+	if len(newPart.AllowWhenOneOf) > 0 {
+		for _, part := range current {
+			if g.refersTo(newPart.AllowWhenOneOf, part) {
+				return ""
+			}
+		}
+		return RejectAllowWhenOneOf
+	}
+
+	return ""
+}
+
+// PruneStats counts the candidate combinations that were accepted and rejected, by rejection reason
+type PruneStats struct {
+	Accepted int
+	Rejected map[string]int
+}
+
+// record counts a candidate combination, where an empty reason means it was accepted
+func (ps *PruneStats) record(reason string) {
+	if reason == "" {
+		ps.Accepted++
+		return
+	}
+	if ps.Rejected == nil {
+		ps.Rejected = make(map[string]int)
+	}
+	ps.Rejected[reason]++
+}
+
+// Write prints the counts in a human readable form
+func (ps *PruneStats) Write(w io.Writer) {
+	rejected := ps.Rejected[RejectIncompatibleWith] + ps.Rejected[RejectAllowWhenOneOf]
+	fmt.Fprintf(w, "combinations accepted: %d\n", ps.Accepted)
+	fmt.Fprintf(w, "combinations rejected: %d\n", rejected)
+	fmt.Fprintf(w, "  %s: %d\n", RejectIncompatibleWith, ps.Rejected[RejectIncompatibleWith])
+	fmt.Fprintf(w, "  %s: %d\n", RejectAllowWhenOneOf, ps.Rejected[RejectAllowWhenOneOf])
+}
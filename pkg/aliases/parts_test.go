@@ -0,0 +1,435 @@
+package aliases
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases []Alias
+		want    map[string][]string
+	}{
+		{
+			name:    "distinct commands",
+			aliases: []Alias{{Name: "kg", Command: "kubectl get"}, {Name: "kd", Command: "kubectl describe"}},
+			want:    map[string][]string{},
+		},
+		{
+			name:    "same alias defined twice",
+			aliases: []Alias{{Name: "kg", Command: "kubectl get"}, {Name: "kg", Command: "kubectl get"}},
+			want:    map[string][]string{},
+		},
+		{
+			name: "two names for one command",
+			aliases: []Alias{
+				{Name: "kcrtr", Command: "kubectl create -o=yaml -f"},
+				{Name: "kcrsar", Command: "kubectl create -o=yaml -f"},
+				{Name: "kg", Command: "kubectl get"},
+			},
+			want: map[string][]string{"kubectl create -o=yaml -f": {"kcrtr", "kcrsar"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FindDuplicates(test.aliases); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAlias(t *testing.T) {
+	tests := map[string]string{
+		"kgpo":       "kgpo",
+		"GPO":        "gpo",
+		"cert-mgr":   "certmgr",
+		"vs.istio":   "vsistio",
+		"my_alias2":  "my_alias2",
+		"ümlaut":     "mlaut",
+		"$(rm -rf)":  "rmrf",
+		"":           "",
+		"--":         "",
+		"Kube-State": "kubestate",
+	}
+	for alias, want := range tests {
+		if got := NormalizeAlias(alias); got != want {
+			t.Errorf("NormalizeAlias(%q) = %q, want %q", alias, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	g := Generator{
+		Commands:  []Part{{Alias: "K", Full: "kubectl"}},
+		Ops:       []Part{{Alias: "G", Full: "get"}},
+		Resources: []Part{{Alias: "Cert-M", Full: "certificates", AllowWhenOneOf: []string{"G"}, IncompatibleWith: []string{"All-NS"}}},
+		Args:      []Part{{Alias: "All-NS", Full: "--all-namespaces"}},
+		// Normalizing mustn't touch the expansions
+		Convenience: []Part{{Alias: "A.R", Full: "api-resources -o=wide"}},
+		Deprecated:  map[string]struct{}{"Cert-M": {}},
+	}
+	g.Normalize()
+
+	want := Generator{
+		Commands:    []Part{{Alias: "k", Full: "kubectl"}},
+		Ops:         []Part{{Alias: "g", Full: "get"}},
+		Resources:   []Part{{Alias: "certm", Full: "certificates", AllowWhenOneOf: []string{"g"}, IncompatibleWith: []string{"allns"}}},
+		Args:        []Part{{Alias: "allns", Full: "--all-namespaces"}},
+		Convenience: []Part{{Alias: "ar", Full: "api-resources -o=wide"}},
+		Deprecated:  map[string]struct{}{"certm": {}},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("got %+v, want %+v", g, want)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("normalized parts don't validate: %v", err)
+	}
+	if got := names(g.Generate()); !reflect.DeepEqual(got, []string{"kgcertm", "kgallns", "kg", "kallns", "k", "kar"}) {
+		t.Errorf("generated %v", got)
+	}
+}
+
+func TestDenyOps(t *testing.T) {
+	tests := []struct {
+		name  string
+		verbs []string
+		want  []string
+	}{
+		{"by alias", []string{"rm"}, []string{"g", "p", "ed"}},
+		{"by kubectl verb", []string{"delete", "patch"}, []string{"g", "ed"}},
+		{"unknown verbs", []string{"scale"}, []string{"g", "rm", "p", "ed"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := Generator{Ops: []Part{
+				{Alias: "g", Full: "get"},
+				{Alias: "rm", Full: "delete"},
+				{Alias: "p", Full: "patch --type=merge"},
+				// An op without an expansion can only be denied by its alias
+				{Alias: "ed", Full: " "},
+			}}
+			g.DenyOps(test.verbs)
+			if got := ResourceTypes(g.Ops); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ops left are %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDenyOpsReachesTools(t *testing.T) {
+	g := Default()
+	helm := Tools["helm"]()
+	g.Tools = append(g.Tools, &helm)
+	g.DenyOps([]string{"uninstall"})
+	for _, alias := range g.Generate() {
+		if slices.Contains(strings.Fields(alias.Command), "uninstall") {
+			t.Errorf("%s still runs %s", alias.Name, alias.Command)
+		}
+	}
+	if err := g.Validate(); err != nil {
+		t.Fatalf("parts don't validate after denying: %v", err)
+	}
+}
+
+func TestKeepOps(t *testing.T) {
+	g := Default()
+	g.KeepOps([]string{"g", "lo"})
+	if got, want := ResourceTypes(g.Ops), []string{"lo", "g"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ops left are %v, want %v", got, want)
+	}
+	if err := g.Validate(); err != nil {
+		t.Fatalf("parts don't validate after keeping: %v", err)
+	}
+}
+
+func TestDenyOpsLeavesNoDestructiveAliases(t *testing.T) {
+	g := Default()
+	g.Ops = append(g.Ops, Part{Alias: "p", Full: "patch"})
+	g.DenyOps([]string{"rm", "patch"})
+	if err := g.Validate(); err != nil {
+		t.Fatalf("parts don't validate after denying: %v", err)
+	}
+	generated := g.Generate()
+	if len(generated) == 0 {
+		t.Fatal("no aliases left")
+	}
+	for _, alias := range generated {
+		if fields := strings.Fields(alias.Command); slices.Contains(fields, "delete") || slices.Contains(fields, "patch") {
+			t.Errorf("%s still runs %s", alias.Name, alias.Command)
+		}
+	}
+}
+
+func TestPruneDangling(t *testing.T) {
+	g := Generator{
+		Ops: []Part{{Alias: "g", Full: "get"}},
+		Resources: []Part{
+			{Alias: "po", Full: "pods", AllowWhenOneOf: []string{"g", "rm"}, IncompatibleWith: []string{"all"}},
+			// Only allowed with ops that are gone
+			{Alias: "job", Full: "job", AllowWhenOneOf: []string{"rm", "cr"}},
+		},
+		Args: []Part{
+			{Alias: "all", Full: "--all", AllowWhenOneOf: []string{"rm"}},
+			// Allowed only with a resource that's pruned itself
+			{Alias: "oyaml", Full: "-o=yaml", AllowWhenOneOf: []string{"job"}},
+			{Alias: "sl", Full: "--show-labels"},
+		},
+	}
+	g.PruneDangling()
+
+	want := Generator{
+		Ops:       []Part{{Alias: "g", Full: "get"}},
+		Resources: []Part{{Alias: "po", Full: "pods", AllowWhenOneOf: []string{"g"}, IncompatibleWith: []string{}}},
+		Args:      []Part{{Alias: "sl", Full: "--show-labels"}},
+	}
+	if !reflect.DeepEqual(g.Resources, want.Resources) || !reflect.DeepEqual(g.Args, want.Args) {
+		t.Errorf("got resources %+v and args %+v, want %+v and %+v", g.Resources, g.Args, want.Resources, want.Args)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("pruned parts don't validate: %v", err)
+	}
+}
+
+func TestFindCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases []Alias
+		want    map[string][]string
+	}{
+		{
+			name:    "distinct names",
+			aliases: []Alias{{Name: "kg", Command: "kubectl get"}, {Name: "kd", Command: "kubectl describe"}},
+			want:    map[string][]string{},
+		},
+		{
+			name:    "same alias defined twice",
+			aliases: []Alias{{Name: "kg", Command: "kubectl get"}, {Name: "kg", Command: "kubectl get"}},
+			want:    map[string][]string{},
+		},
+		{
+			name: "one name for two commands",
+			aliases: []Alias{
+				{Name: "kgcs", Command: "kubectl get certificates"},
+				{Name: "kgcs", Command: "kubectl get componentstatuses"},
+				{Name: "kgcs", Command: "kubectl get certificates"},
+				{Name: "kg", Command: "kubectl get"},
+			},
+			want: map[string][]string{"kgcs": {"kubectl get certificates", "kubectl get componentstatuses"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FindCollisions(test.aliases); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDedup(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases []Alias
+		want    []Alias
+	}{
+		{
+			name:    "unique names",
+			aliases: []Alias{{Name: "kg", Command: "kubectl get"}, {Name: "kd", Command: "kubectl describe"}},
+			want:    []Alias{{Name: "kg", Command: "kubectl get"}, {Name: "kd", Command: "kubectl describe"}},
+		},
+		{
+			name: "keeps the first of each name in order",
+			aliases: []Alias{
+				{Name: "kgcs", Command: "kubectl get certificates"},
+				{Name: "kg", Command: "kubectl get"},
+				{Name: "kgcs", Command: "kubectl get componentstatuses"},
+				{Name: "kg", Command: "kubectl get"},
+			},
+			want: []Alias{{Name: "kgcs", Command: "kubectl get certificates"}, {Name: "kg", Command: "kubectl get"}},
+		},
+		{
+			name:    "no aliases",
+			aliases: nil,
+			want:    nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Dedup(test.aliases); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// shortcutGenerator has the sys shortcut, a mon shortcut added next to it and parts with rules against sys and all
+func shortcutGenerator() Generator {
+	return Generator{
+		Commands: []Part{{Alias: "k", Full: "kubectl"}},
+		GlobalOps: []Part{
+			NamespaceShortcut("sys", "kube-system"),
+			NamespaceShortcut("mon", "monitoring"),
+			{Alias: "v", Full: "--v=9"},
+		},
+		Ops: []Part{{Alias: "g", Full: "get"}, {Alias: "rm", Full: "delete", IncompatibleWith: []string{"sys"}}},
+		Resources: []Part{
+			{Alias: "po", Full: "pods"},
+			{Alias: "no", Full: "nodes", IncompatibleWith: []string{"sys", "all"}},
+		},
+		Args: []Part{
+			{Alias: "all", Full: "--all-namespaces"},
+			// Not a global op, so not a namespace shortcut even though it sets the namespace
+			{Alias: "dev", Full: "--namespace=dev"},
+		},
+	}
+}
+
+func TestRejectionReasonIsSymmetric(t *testing.T) {
+	g := shortcutGenerator()
+	part := func(alias string) Part {
+		for _, group := range g.Stages() {
+			for _, part := range *group {
+				if part.Alias == alias {
+					return part
+				}
+			}
+		}
+		t.Fatalf("no part %s", alias)
+		return Part{}
+	}
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"sys", "rm", RejectIncompatibleWith},
+		{"mon", "rm", RejectIncompatibleWith},
+		{"no", "all", RejectIncompatibleWith},
+		{"sys", "no", RejectIncompatibleWith},
+		{"v", "rm", ""},
+		{"g", "no", ""},
+		{"rm", "po", ""},
+		{"dev", "rm", ""},
+	}
+	for _, test := range tests {
+		for _, order := range [][2]string{{test.a, test.b}, {test.b, test.a}} {
+			if got := g.RejectionReason([]Part{part(order[0])}, part(order[1])); got != test.want {
+				t.Errorf("adding %s to %s is rejected with %q, want %q", order[1], order[0], got, test.want)
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, alias := range g.Generate() {
+		names[alias.Name] = true
+	}
+	for _, name := range []string{"ksysrm", "kmonrm", "kgnoall", "ksysgno", "kmongno"} {
+		if names[name] {
+			t.Errorf("%s is generated despite an incompatibility", name)
+		}
+	}
+	for _, name := range []string{"krm", "kvrm", "kmongpo", "kgno", "krmpodev"} {
+		if !names[name] {
+			t.Errorf("%s isn't generated", name)
+		}
+	}
+}
+
+func TestRefersToNamespaceShortcuts(t *testing.T) {
+	g := shortcutGenerator()
+	tests := []struct {
+		aliases []string
+		part    Part
+		want    bool
+	}{
+		{[]string{"sys"}, g.GlobalOps[0], true},
+		{[]string{"sys"}, g.GlobalOps[1], true},
+		{[]string{"mon"}, g.GlobalOps[0], true},
+		{[]string{"sys"}, g.GlobalOps[2], false},
+		{[]string{"v"}, g.GlobalOps[2], true},
+		{[]string{"sys"}, g.Args[1], false},
+		{[]string{"dev"}, g.GlobalOps[0], false},
+		{nil, g.GlobalOps[0], false},
+	}
+	for _, test := range tests {
+		if got := g.refersTo(test.aliases, test.part); got != test.want {
+			t.Errorf("refersTo(%v, %s) = %v, want %v", test.aliases, test.part.Alias, got, test.want)
+		}
+	}
+}
+
+func TestSuggestFixes(t *testing.T) {
+	g := Default()
+	if got := g.SuggestFixes(); len(got) != 1 || got[0].Part.Full != "--all" || got[0].Alias != "ala" {
+		t.Errorf("got %+v, want the second all to become ala", got)
+	}
+
+	g = Generator{
+		Ops: []Part{{Alias: "g", Full: "get"}, {Alias: "g", Full: "edit"}, {Alias: "a", Full: "apply"}},
+		Args: []Part{
+			{Alias: "ow", Full: "-o=wide"},
+			{Alias: "ow", Full: "--watch"},
+			{Alias: "ow", Full: "--watch-only"},
+			{Alias: "oa", Full: "-o=yaml"},
+		},
+		Resources: []Part{{Alias: "po", Full: "pods"}},
+		PosArgs:   []Part{{Alias: "po", Full: "--pod"}},
+	}
+	var got []string
+	for _, suggestion := range g.SuggestFixes() {
+		got = append(got, suggestion.Part.Full+"="+suggestion.Alias)
+	}
+	// a is taken so the second g gets b, and oa is so the second ow gets ob and the third the next free one. The
+	// same alias in another group isn't a collision
+	if want := []string{"edit=b", "--watch=ob", "--watch-only=oc"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestUnusedAlias(t *testing.T) {
+	taken := map[string]struct{}{"ala": {}, "alb": {}}
+	for letter := 'a'; letter <= 'z'; letter++ {
+		taken["x"+string(letter)] = struct{}{}
+	}
+	tests := []struct {
+		alias string
+		want  string
+	}{
+		{"all", "alc"},
+		{"xy", "xya"},
+		{"", "a"},
+	}
+	for _, test := range tests {
+		if got, found := unusedAlias(test.alias, taken); !found || got != test.want {
+			t.Errorf("unusedAlias(%q) = %q, want %q", test.alias, got, test.want)
+		}
+	}
+}
+
+func TestPruneStats(t *testing.T) {
+	g := Generator{
+		Commands:  []Part{{Alias: "k", Full: "kubectl"}},
+		Ops:       []Part{{Alias: "g", Full: "get"}, {Alias: "d", Full: "describe"}},
+		Resources: []Part{{Alias: "po", Full: "pods", AllowWhenOneOf: []string{"g", "d"}}},
+		Args:      []Part{{Alias: "w", Full: "--watch", AllowWhenOneOf: []string{"g", "d"}, IncompatibleWith: []string{"d"}}},
+	}
+	// kgpo, kgpow, kgw and kdpo are accepted on top of g and d themselves, d rules out w twice, and po and w need
+	// an operation
+	if got := names(g.Generate()); !slices.Equal(got, []string{"kgpow", "kgpo", "kgw", "kg", "kdpo", "kd", "k"}) {
+		t.Fatalf("generated %v", got)
+	}
+	want := PruneStats{Accepted: 6, Rejected: map[string]int{RejectIncompatibleWith: 2, RejectAllowWhenOneOf: 2}}
+	if !reflect.DeepEqual(g.Pruned, want) {
+		t.Errorf("got %+v, want %+v", g.Pruned, want)
+	}
+
+	var out strings.Builder
+	g.Pruned.Write(&out)
+	report := "combinations accepted: 6\ncombinations rejected: 4\n  incompatible-with: 2\n  allow-when-one-of: 2\n"
+	if out.String() != report {
+		t.Errorf("got report\n%s\nwant\n%s", out.String(), report)
+	}
+}